@@ -0,0 +1,195 @@
+package gowurfl
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// IsRobot reports whether the device is a robot, crawler or other automated
+// client according to the is_robot virtual capability.
+func (d *Device) IsRobot() (bool, error) {
+	return isRobot(d.GetVirtualCapability)
+}
+
+// IsRobot is like Device.IsRobot.
+func (s *Snapshot) IsRobot() (bool, error) {
+	return isRobot(s.GetVirtualCapability)
+}
+
+func isRobot(get func(string) (string, error)) (bool, error) {
+	v, err := get("is_robot")
+	if err != nil {
+		return false, err
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("is_robot: %q: %w", v, ErrorInvalidCapabilityValue)
+	}
+
+	return b, nil
+}
+
+// SearchEngine describes the crawler of a search engine. Match holds lower
+// case words identifying it in the device id or name of a robot, Domains the
+// suffixes of the host names its crawlers resolve to.
+type SearchEngine struct {
+	Name    string
+	Match   []string
+	Domains []string
+}
+
+// SearchEngines are the crawlers BotInfo knows, in the order they are tried.
+var SearchEngines = []SearchEngine{
+	{"Google", []string{"google", "googlebot"}, []string{".googlebot.com", ".google.com", ".googleusercontent.com"}},
+	{"Bing", []string{"bing", "bingbot", "msnbot"}, []string{".search.msn.com"}},
+	{"Yahoo", []string{"yahoo", "slurp"}, []string{".crawl.yahoo.net"}},
+	{"Yandex", []string{"yandex", "yandexbot"}, []string{".yandex.ru", ".yandex.net", ".yandex.com"}},
+	{"Baidu", []string{"baidu", "baiduspider"}, []string{".crawl.baidu.com", ".crawl.baidu.jp"}},
+	{"Apple", []string{"applebot"}, []string{".applebot.apple.com"}},
+	{"DuckDuckGo", []string{"duckduckgo", "duckduckbot"}, nil},
+}
+
+// BotInfo describes a robot.
+type BotInfo struct {
+	// ID is the device id, Name the complete_device_name of the robot.
+	ID   string
+	Name string
+	// SearchEngine is the name of the matching entry of SearchEngines, empty
+	// if the robot is not a known search engine crawler.
+	SearchEngine string
+	// Verified is set by Verify if the client address belongs to the
+	// search engine.
+	Verified bool
+
+	domains []string
+}
+
+// IsSearchEngine reports whether the robot is a known search engine crawler.
+func (bi *BotInfo) IsSearchEngine() bool {
+	return bi.SearchEngine != ""
+}
+
+// BotInfo returns information about the robot d, nil if d is not a robot. The
+// search engine is recognized by the words of the device id and name, e.g.
+// "googlebot" in "Googlebot-Image", but not in "NotGooglebot".
+func (w *WURFL) BotInfo(d *Device) (*BotInfo, error) {
+	id, err := d.GetID()
+	if err != nil {
+		return nil, err
+	}
+
+	return botInfo(id, d.GetVirtualCapability)
+}
+
+func botInfo(id string, get func(string) (string, error)) (*BotInfo, error) {
+	robot, err := isRobot(get)
+	if err != nil || !robot {
+		return nil, err
+	}
+
+	name, err := get("complete_device_name")
+	if err != nil {
+		return nil, err
+	}
+
+	bi := &BotInfo{ID: id, Name: name}
+	if se := matchSearchEngine(id, name); se != nil {
+		bi.SearchEngine = se.Name
+		bi.domains = se.Domains
+	}
+
+	return bi, nil
+}
+
+// matchSearchEngine returns the first search engine with a word of any of the
+// names in its Match. Words are the runs of letters and digits.
+func matchSearchEngine(names ...string) *SearchEngine {
+	var words []string
+	for _, n := range names {
+		words = append(words, strings.FieldsFunc(strings.ToLower(n), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	}
+
+	for i, se := range SearchEngines {
+		for _, m := range se.Match {
+			for _, w := range words {
+				if w == m {
+					return &SearchEngines[i]
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Resolver does the DNS lookups for Verify, usually a *net.Resolver.
+type Resolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// Verify checks with a reverse and a forward confirming DNS lookup whether ip
+// belongs to the search engine the robot claims to be, and sets Verified
+// accordingly. Robots that are no known search engine or whose search engine
+// lists no domains are never verified. Addresses are compared as IPs, so any
+// notation of an IPv6 address matches. A nil resolver uses
+// net.DefaultResolver.
+func (bi *BotInfo) Verify(ctx context.Context, r Resolver, ip string) error {
+	bi.Verified = false
+
+	if len(bi.domains) == 0 {
+		return nil
+	}
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return fmt.Errorf("invalid IP address %q", ip)
+	}
+
+	if r == nil {
+		r = net.DefaultResolver
+	}
+
+	hosts, err := r.LookupAddr(ctx, ip)
+	if err != nil {
+		return err
+	}
+
+	for _, h := range hosts {
+		h = strings.TrimSuffix(strings.ToLower(h), ".")
+		if !hasDomain(h, bi.domains) {
+			continue
+		}
+
+		addrs, err := r.LookupHost(ctx, h)
+		if err != nil {
+			return err
+		}
+
+		for _, a := range addrs {
+			if addr.Equal(net.ParseIP(a)) {
+				bi.Verified = true
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+func hasDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		if strings.HasSuffix(host, d) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,233 @@
+package gowurfl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const googlebot = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+
+func TestSnapshotIsRobot(t *testing.T) {
+	tcs := []struct {
+		value string
+		robot bool
+		err   error
+	}{
+		{"true", true, nil},
+		{"false", false, nil},
+		{"yes", false, ErrorInvalidCapabilityValue},
+	}
+
+	for _, tc := range tcs {
+		s := &Snapshot{virtualCapabilities: Capabilities{"is_robot": tc.value}}
+
+		robot, err := s.IsRobot()
+		if robot != tc.robot || !errors.Is(err, tc.err) {
+			t.Errorf("IsRobot() with %q returned %v, %v, want %v, %v", tc.value, robot, err, tc.robot, tc.err)
+		}
+	}
+}
+
+func TestMatchSearchEngine(t *testing.T) {
+	tcs := []struct {
+		id   string
+		name string
+		want string
+	}{
+		{"google_bot_ver1", "Googlebot", "Google"},
+		{"googlebot_image_ver1", "Googlebot-Image", "Google"},
+		{"msnbot_ver2", "MSNBot", "Bing"},
+		{"yahoo_slurp_ver1", "Yahoo! Slurp", "Yahoo"},
+		{"generic_web_crawler", "BaiduSpider", "Baidu"},
+		{"ahrefs_bot", "AhrefsBot", ""},
+		{"notgoogle_crawler", "NotGooglebot", ""},
+		{"bingo_crawler", "Bingo", ""},
+		{"generic_web_crawler", "Applebotanist", ""},
+	}
+
+	for _, tc := range tcs {
+		se := matchSearchEngine(tc.id, tc.name)
+
+		name := ""
+		if se != nil {
+			name = se.Name
+		}
+
+		if name != tc.want {
+			t.Errorf("matchSearchEngine(%q, %q) returned %q, want %q", tc.id, tc.name, name, tc.want)
+		}
+	}
+}
+
+type fakeResolver struct {
+	addrs map[string][]string
+	hosts map[string][]string
+}
+
+func (r fakeResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	return r.addrs[addr], nil
+}
+
+func (r fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return r.hosts[host], nil
+}
+
+func TestBotInfoVerify(t *testing.T) {
+	r := fakeResolver{
+		addrs: map[string][]string{
+			"66.249.66.1":                 {"crawl-66-249-66-1.googlebot.com."},
+			"10.0.0.1":                    {"crawl-66-249-66-1.googlebot.com."},
+			"10.0.0.2":                    {"googlebot.example.com."},
+			"2001:4860:4801:10::1":        {"crawl-2001-4860-4801-10--1.googlebot.com."},
+			"2001:4860:4801:0010:0:0:0:2": {"crawl-2001-4860-4801-10--2.googlebot.com."},
+			"::ffff:66.249.66.1":          {"crawl-66-249-66-1.googlebot.com."},
+		},
+		hosts: map[string][]string{
+			"crawl-66-249-66-1.googlebot.com":          {"66.249.66.1"},
+			"crawl-2001-4860-4801-10--1.googlebot.com": {"2001:4860:4801:0010:0000:0000:0000:0001"},
+			"crawl-2001-4860-4801-10--2.googlebot.com": {"2001:4860:4801:10::2"},
+		},
+	}
+
+	tcs := []struct {
+		ip       string
+		verified bool
+	}{
+		{"66.249.66.1", true},
+		// reverse lookup matches, forward lookup does not confirm it
+		{"10.0.0.1", false},
+		{"10.0.0.2", false},
+		{"10.0.0.3", false},
+		// the forward lookup returns another notation of the same address
+		{"2001:4860:4801:10::1", true},
+		{"2001:4860:4801:0010:0:0:0:2", true},
+		{"::ffff:66.249.66.1", true},
+	}
+
+	for _, tc := range tcs {
+		bi := &BotInfo{Name: "Google Bot", SearchEngine: "Google", domains: SearchEngines[0].Domains}
+
+		if err := bi.Verify(context.Background(), r, tc.ip); err != nil {
+			t.Fatal(err)
+		}
+
+		if bi.Verified != tc.verified {
+			t.Errorf("Verify(%q) set Verified to %v, want %v", tc.ip, bi.Verified, tc.verified)
+		}
+	}
+}
+
+func TestBotInfoVerifyInvalid(t *testing.T) {
+	bi := &BotInfo{Name: "Google Bot", SearchEngine: "Google", domains: SearchEngines[0].Domains}
+
+	if err := bi.Verify(context.Background(), fakeResolver{}, "crawler"); err == nil || bi.Verified {
+		t.Errorf("Verify(%q) returned %v and set Verified to %v", "crawler", err, bi.Verified)
+	}
+}
+
+func TestBotInfo(t *testing.T) {
+	w := testNewEngine(t)
+	defer w.Close()
+	testLoadRepository(rootFile, w, t)
+
+	d, err := w.LookupUserAgent(googlebot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	bi, err := w.BotInfo(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bi == nil || bi.SearchEngine != "Google" {
+		t.Errorf("BotInfo() for %q returned %+v", googlebot, bi)
+	}
+
+	d, err = w.LookupUserAgent(uas[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if bi, err := w.BotInfo(d); bi != nil || err != nil {
+		t.Errorf("BotInfo() for %q returned %+v, %v", uas[2], bi, err)
+	}
+}
+
+func TestRobotHandler(t *testing.T) {
+	w := testNewEngine(t)
+	defer w.Close()
+	testLoadRepository(rootFile, w, t)
+
+	var tagged *BotInfo
+	next := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		tagged = BotInfoFromContext(r.Context())
+	})
+
+	tcs := []struct {
+		ua     string
+		opts   []RobotOption
+		status int
+		robot  bool
+	}{
+		{googlebot, nil, http.StatusOK, true},
+		{googlebot, []RobotOption{RejectRobots(http.StatusForbidden)}, http.StatusForbidden, false},
+		{googlebot, []RobotOption{RejectRobots(http.StatusForbidden), AllowSearchEngines()}, http.StatusOK, true},
+		{uas[2], []RobotOption{RejectRobots(http.StatusForbidden)}, http.StatusOK, false},
+	}
+
+	for _, tc := range tcs {
+		tagged = nil
+
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("User-Agent", tc.ua)
+		rec := httptest.NewRecorder()
+
+		w.RobotHandler(next, tc.opts...).ServeHTTP(rec, r)
+
+		if rec.Code != tc.status || (tagged != nil) != tc.robot {
+			t.Errorf("RobotHandler() for %q returned %d and tagged %v, want %d and %v", tc.ua, rec.Code, tagged, tc.status, tc.robot)
+		}
+	}
+}
+
+func TestRobotHandlerTagHeader(t *testing.T) {
+	w := testNewEngine(t)
+	defer w.Close()
+	testLoadRepository(rootFile, w, t)
+
+	var header string
+	next := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Robot")
+	})
+	h := w.RobotHandler(next, TagRobots("X-Robot"))
+
+	for _, ua := range []string{googlebot, uas[2]} {
+		header = ""
+
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("User-Agent", ua)
+		r.Header.Set("X-Robot", "forged")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+
+		if header == "forged" {
+			t.Errorf("RobotHandler() passed on the client's tag for %q", ua)
+		}
+	}
+}
+
+func TestRejectRobotsInvalidStatus(t *testing.T) {
+	for _, status := range []int{0, -1, 99, 1000} {
+		var o robotOptions
+		RejectRobots(status)(&o)
+
+		if o.status != http.StatusForbidden {
+			t.Errorf("RejectRobots(%d) set status %d, want %d", status, o.status, http.StatusForbidden)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package gowurfl
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// CacheStats describes the effectiveness of the libwurfl cache configured with
+// SetCacheProvider. libwurfl does not expose any counters itself, so they are
+// tracked in Go: every device returned by LookupUserAgent with
+// MatchTypeCached counts as a hit, every other lookup as a miss.
+//
+// There are no Evictions or Entries: libwurfl neither reports its current
+// number of entries nor when one is evicted, and neither can be derived from
+// the lookups. ResultCacheStats has both for the Go side ResultCache.
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// HitRate returns the share of lookups served from the cache.
+func (s CacheStats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+
+	return float64(s.Hits) / float64(total)
+}
+
+type cacheStats struct {
+	mu       sync.Mutex
+	provider CacheProvider
+	sizes    []int
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+func (cs *cacheStats) reset(c CacheProvider, sizes []int) {
+	cs.mu.Lock()
+	cs.provider = c
+	cs.sizes = append([]int(nil), sizes...)
+	cs.mu.Unlock()
+
+	cs.hits.Store(0)
+	cs.misses.Store(0)
+}
+
+func (cs *cacheStats) record(hit bool) {
+	if hit {
+		cs.hits.Add(1)
+	} else {
+		cs.misses.Add(1)
+	}
+}
+
+// GetCacheProvider returns the active cache provider together with the sizes
+// it was configured with. The sizes are the ones actually passed to libwurfl,
+// i.e. the defaults if SetCacheProvider was called without enough of them.
+func (w *WURFL) GetCacheProvider() (CacheProvider, []int) {
+	w.cacheStats.mu.Lock()
+	defer w.cacheStats.mu.Unlock()
+
+	return w.cacheStats.provider, append([]int(nil), w.cacheStats.sizes...)
+}
+
+// CacheStats returns the counters for the libwurfl cache since the last call
+// to SetCacheProvider.
+func (w *WURFL) CacheStats() CacheStats {
+	return CacheStats{
+		Hits:   w.cacheStats.hits.Load(),
+		Misses: w.cacheStats.misses.Load(),
+	}
+}
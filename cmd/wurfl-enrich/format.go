@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// record is a single log entry. Line based formats keep the raw line, CSV the
+// parsed fields. Malformed records are not looked up and written back
+// unchanged.
+type record struct {
+	line      string
+	fields    []string
+	ua        string
+	malformed bool
+}
+
+// format reads records in one of the supported log formats and writes them
+// back with the looked up values appended.
+type format interface {
+	Read() (*record, error)
+	Write(r *record, values []string) error
+	Flush() error
+}
+
+type formatOptions struct {
+	// uaField is the JSON key or CSV column holding the user agent.
+	uaField string
+	// columns are the names of the appended values, used for the CSV header
+	// and as JSON keys.
+	columns []string
+	// header is set if CSV input has a header line.
+	header bool
+	// skipHeader suppresses writing the enriched CSV header, so it is only
+	// written once for multiple inputs.
+	skipHeader bool
+}
+
+func newFormat(name string, r io.Reader, w io.Writer, opts formatOptions) (format, error) {
+	switch name {
+	default:
+		return nil, fmt.Errorf("unknown format %q", name)
+	case "clf":
+		return &clfFormat{lineFormat: newLineFormat(r, w)}, nil
+	case "jsonl":
+		return &jsonlFormat{lineFormat: newLineFormat(r, w), opts: opts}, nil
+	case "csv":
+		in := &rawReader{r: r}
+		cr := csv.NewReader(in)
+		cr.FieldsPerRecord = -1
+
+		return &csvFormat{r: cr, in: in, w: csv.NewWriter(w), out: w, opts: opts, uaCol: -1}, nil
+	}
+}
+
+type lineFormat struct {
+	s *bufio.Scanner
+	w *bufio.Writer
+}
+
+func newLineFormat(r io.Reader, w io.Writer) lineFormat {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	return lineFormat{s: s, w: bufio.NewWriter(w)}
+}
+
+func (f *lineFormat) readLine() (string, error) {
+	if !f.s.Scan() {
+		if err := f.s.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+
+	return f.s.Text(), nil
+}
+
+// writeMalformed writes the line of a malformed record back unchanged.
+func (f *lineFormat) writeMalformed(r *record) error {
+	f.w.WriteString(r.line)
+	return f.w.WriteByte('\n')
+}
+
+func (f *lineFormat) Flush() error {
+	return f.w.Flush()
+}
+
+// clfFormat handles the Combined Log Format, where the user agent is the last
+// quoted field:
+//
+//	host ident user [time] "request" status bytes "referer" "user agent"
+type clfFormat struct {
+	lineFormat
+}
+
+func (f *clfFormat) Read() (*record, error) {
+	line, err := f.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	// lines without the user agent field are not in Combined Log Format
+	fields := splitCLF(line)
+	if len(fields) < 9 {
+		return &record{line: line, malformed: true}, nil
+	}
+
+	return &record{line: line, ua: fields[8]}, nil
+}
+
+func (f *clfFormat) Write(r *record, values []string) error {
+	if r.malformed {
+		return f.writeMalformed(r)
+	}
+
+	f.w.WriteString(r.line)
+
+	for _, v := range values {
+		f.w.WriteByte(' ')
+		f.w.WriteString(strconv.Quote(v))
+	}
+
+	return f.w.WriteByte('\n')
+}
+
+// splitCLF splits a log line into its fields. Quoted fields are unquoted,
+// bracketed ones are returned without the brackets.
+func splitCLF(line string) []string {
+	var fields []string
+
+	for i := 0; i < len(line); {
+		switch line[i] {
+		case ' ':
+			i++
+		case '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(line) && line[j] != '"'; j++ {
+				if line[j] == '\\' && j+1 < len(line) {
+					j++
+				}
+				b.WriteByte(line[j])
+			}
+			fields = append(fields, b.String())
+			i = j + 1
+		case '[':
+			j := strings.IndexByte(line[i:], ']')
+			if j < 0 {
+				j = len(line) - i
+			}
+			fields = append(fields, line[i+1:i+j])
+			i += j + 1
+		default:
+			j := strings.IndexByte(line[i:], ' ')
+			if j < 0 {
+				j = len(line) - i
+			}
+			fields = append(fields, line[i:i+j])
+			i += j
+		}
+	}
+
+	return fields
+}
+
+// jsonlFormat handles one JSON object per line. The values are added as an
+// object under the "wurfl" key, the rest of the line is left untouched. Lines
+// without a string user agent are malformed.
+type jsonlFormat struct {
+	lineFormat
+	opts formatOptions
+}
+
+func (f *jsonlFormat) Read() (*record, error) {
+	line, err := f.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	// anything but an object with a string user agent is malformed, including
+	// null, which unmarshals into a nil map
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(line), &obj); err != nil || obj == nil {
+		return &record{line: line, malformed: true}, nil
+	}
+
+	raw, ok := obj[f.opts.uaField]
+	if !ok {
+		return &record{line: line, malformed: true}, nil
+	}
+
+	// a null user agent is missing as well
+	var ua *string
+	if err := json.Unmarshal(raw, &ua); err != nil || ua == nil {
+		return &record{line: line, malformed: true}, nil
+	}
+
+	return &record{line: strings.TrimSpace(line), ua: *ua}, nil
+}
+
+func (f *jsonlFormat) Write(r *record, values []string) error {
+	if r.malformed {
+		return f.writeMalformed(r)
+	}
+
+	add := make(map[string]string, len(values))
+	for i, v := range values {
+		add[f.opts.columns[i]] = v
+	}
+
+	b, err := json.Marshal(add)
+	if err != nil {
+		return err
+	}
+
+	line := strings.TrimSuffix(r.line, "}")
+	if strings.TrimSpace(line) != "{" {
+		line += ","
+	}
+
+	f.w.WriteString(line)
+	f.w.WriteString(`"wurfl":`)
+	f.w.Write(b)
+
+	_, err = f.w.WriteString("}\n")
+	return err
+}
+
+// csvFormat handles comma separated values. The user agent column is looked
+// up by name in the header, or given by its index without a header. Rows may
+// have any number of fields, rows that cannot be parsed or lack the user agent
+// column are malformed.
+type csvFormat struct {
+	r     *csv.Reader
+	in    *rawReader
+	w     *csv.Writer
+	out   io.Writer
+	opts  formatOptions
+	uaCol int
+}
+
+func (f *csvFormat) Read() (*record, error) {
+	if f.uaCol < 0 {
+		if err := f.init(); err != nil {
+			return nil, err
+		}
+	}
+
+	fields, err := f.r.Read()
+	line := f.in.take(f.r.InputOffset())
+
+	var perr *csv.ParseError
+	if errors.As(err, &perr) {
+		return &record{line: line, malformed: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if f.uaCol >= len(fields) {
+		return &record{line: line, malformed: true}, nil
+	}
+
+	return &record{fields: fields, ua: fields[f.uaCol]}, nil
+}
+
+func (f *csvFormat) init() error {
+	if !f.opts.header {
+		col, err := strconv.Atoi(f.opts.uaField)
+		if err != nil || col < 0 {
+			return fmt.Errorf("without header the user agent field must be a column index, not %q", f.opts.uaField)
+		}
+
+		f.uaCol = col
+		return nil
+	}
+
+	header, err := f.r.Read()
+	f.in.take(f.r.InputOffset())
+	if err != nil {
+		if err == io.EOF {
+			return errors.New("missing CSV header")
+		}
+		return err
+	}
+
+	for i, h := range header {
+		if h == f.opts.uaField {
+			f.uaCol = i
+		}
+	}
+
+	if f.uaCol < 0 {
+		return fmt.Errorf("no column %q in CSV header", f.opts.uaField)
+	}
+
+	if f.opts.skipHeader {
+		return nil
+	}
+
+	return f.w.Write(append(header, f.opts.columns...))
+}
+
+func (f *csvFormat) Write(r *record, values []string) error {
+	if !r.malformed {
+		return f.w.Write(append(r.fields, values...))
+	}
+
+	// the raw row bypasses the CSV writer, which must not hold anything back
+	if err := f.Flush(); err != nil {
+		return err
+	}
+
+	line := r.line
+	if !strings.HasSuffix(line, "\n") {
+		line += "\n"
+	}
+
+	_, err := io.WriteString(f.out, line)
+	return err
+}
+
+func (f *csvFormat) Flush() error {
+	f.w.Flush()
+	return f.w.Error()
+}
+
+// rawReader keeps what was read from r until it is taken, so the text of a
+// CSV row can be recovered from the offsets of csv.Reader.
+type rawReader struct {
+	r    io.Reader
+	buf  []byte
+	base int64
+}
+
+func (rr *rawReader) Read(p []byte) (int, error) {
+	n, err := rr.r.Read(p)
+	rr.buf = append(rr.buf, p[:n]...)
+
+	return n, err
+}
+
+// take returns the input up to offset not taken yet.
+func (rr *rawReader) take(offset int64) string {
+	n := offset - rr.base
+	s := string(rr.buf[:n])
+
+	rr.buf = append(rr.buf[:0], rr.buf[n:]...)
+	rr.base = offset
+
+	return s
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const clfLine = `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /index.html HTTP/1.0" 200 2326 "http://example.com/" "Mozilla/5.0 (Linux; Android 4.4.2) \"quoted\""`
+
+func TestSplitCLF(t *testing.T) {
+	want := []string{
+		"127.0.0.1", "-", "frank", "10/Oct/2000:13:55:36 -0700",
+		"GET /index.html HTTP/1.0", "200", "2326", "http://example.com/",
+		`Mozilla/5.0 (Linux; Android 4.4.2) "quoted"`,
+	}
+
+	if fields := splitCLF(clfLine); !reflect.DeepEqual(fields, want) {
+		t.Errorf("splitCLF()\nwant: %q\nhave: %q", want, fields)
+	}
+}
+
+// testEnrich reads all records of the input, appends the user agent upper
+// cased and returns the output.
+func testEnrich(t *testing.T, name, in string, opts formatOptions) string {
+	var out bytes.Buffer
+
+	f, err := newFormat(name, strings.NewReader(in), &out, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		r, err := f.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := f.Write(r, []string{strings.ToUpper(r.ua)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := f.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	return out.String()
+}
+
+func TestFormats(t *testing.T) {
+	tcs := []struct {
+		name string
+		opts formatOptions
+		in   string
+		out  string
+	}{
+		{
+			"clf",
+			formatOptions{},
+			`1.2.3.4 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.0" 200 1 "-" "Dillo/2.0"` + "\n",
+			`1.2.3.4 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.0" 200 1 "-" "Dillo/2.0" "DILLO/2.0"` + "\n",
+		},
+		{
+			"clf",
+			formatOptions{},
+			`1.2.3.4 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.0" 200 1` + "\n",
+			`1.2.3.4 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.0" 200 1` + "\n",
+		},
+		{
+			"jsonl",
+			formatOptions{uaField: "ua", columns: []string{"id"}},
+			`{"ua": "Dillo/2.0", "status": 200}` + "\n" + `{}` + "\n",
+			`{"ua": "Dillo/2.0", "status": 200,"wurfl":{"id":"DILLO/2.0"}}` + "\n" + `{}` + "\n",
+		},
+		{
+			"jsonl",
+			formatOptions{uaField: "ua", columns: []string{"id"}},
+			`{"ua": "Dillo/2.0"` + "\n" + `{"ua": "Dillo/2.0"}` + "\n",
+			`{"ua": "Dillo/2.0"` + "\n" + `{"ua": "Dillo/2.0","wurfl":{"id":"DILLO/2.0"}}` + "\n",
+		},
+		{
+			"jsonl",
+			formatOptions{uaField: "ua", columns: []string{"id"}},
+			"null\n[1]\n" + `{"ua": 42}` + "\n" + `{"ua": null}` + "\n",
+			"null\n[1]\n" + `{"ua": 42}` + "\n" + `{"ua": null}` + "\n",
+		},
+		{
+			"csv",
+			formatOptions{uaField: "ua", columns: []string{"id"}, header: true},
+			"status,ua\n200,Dillo/2.0\n",
+			"status,ua,id\n200,Dillo/2.0,DILLO/2.0\n",
+		},
+		{
+			"csv",
+			formatOptions{uaField: "ua", columns: []string{"id"}, header: true, skipHeader: true},
+			"status,ua\n200,Dillo/2.0\n",
+			"200,Dillo/2.0,DILLO/2.0\n",
+		},
+		{
+			"csv",
+			formatOptions{uaField: "1", columns: []string{"id"}},
+			"200,Dillo/2.0\n",
+			"200,Dillo/2.0,DILLO/2.0\n",
+		},
+	}
+
+	for _, tc := range tcs {
+		if out := testEnrich(t, tc.name, tc.in, tc.opts); out != tc.out {
+			t.Errorf("%s\nwant: %q\nhave: %q", tc.name, tc.out, out)
+		}
+	}
+}
+
+func TestCSVMalformed(t *testing.T) {
+	in := "status,ua\n200,Dillo/2.0\n404,\"Dillo\"/2.0\"\n500\n200,NetSurf/2.0,extra\n"
+	want := "status,ua,id\n200,Dillo/2.0,DILLO/2.0\n404,\"Dillo\"/2.0\"\n500\n200,NetSurf/2.0,extra,NETSURF/2.0\n"
+
+	out := testEnrich(t, "csv", in, formatOptions{uaField: "ua", columns: []string{"id"}, header: true})
+	if out != want {
+		t.Errorf("csv\nwant: %q\nhave: %q", want, out)
+	}
+}
+
+func TestCSVMissingColumn(t *testing.T) {
+	f, err := newFormat("csv", strings.NewReader("status,agent\n"), io.Discard, formatOptions{uaField: "ua", header: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Read(); err == nil {
+		t.Errorf("Read() expected to fail for a missing user agent column")
+	}
+}
+
+func TestEnricherMalformed(t *testing.T) {
+	in := "{\"ua\": \nnot json\n"
+
+	var out bytes.Buffer
+	f, err := newFormat("jsonl", strings.NewReader(in), &out, formatOptions{uaField: "ua", columns: []string{"wurfl_id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// malformed records are never looked up, so no engine is needed
+	e := &enricher{workers: 2}
+	if err := e.run(f); err != nil {
+		t.Fatalf("run() failed with: %s", err)
+	}
+
+	if e.malformed != 2 {
+		t.Errorf("expected 2 malformed lines but counted %d", e.malformed)
+	}
+
+	if out.String() != in {
+		t.Errorf("malformed lines were not passed through\nwant: %q\nhave: %q", in, out.String())
+	}
+}
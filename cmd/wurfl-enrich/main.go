@@ -0,0 +1,260 @@
+// Command wurfl-enrich appends device information to access logs.
+//
+// It reads Combined Log Format, JSON lines or CSV from the files given as
+// arguments, or from stdin if there are none, looks up the user agent of every
+// record and writes the records to stdout with the device id and the chosen
+// capabilities and virtual capabilities appended. Malformed lines, such as
+// invalid JSON or log lines without a user agent, are counted and written
+// back unchanged.
+//
+// Usage:
+//
+//	wurfl-enrich [flags] [file ...]
+//
+// For example:
+//
+//	wurfl-enrich -format jsonl -ua-field agent -vcaps form_factor,is_mobile access.jsonl
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/knarz/gowurfl"
+	"github.com/knarz/gowurfl/configfile"
+)
+
+var (
+	root       = flag.String("root", "/usr/share/wurfl/wurfl.xml", "WURFL root `file`")
+	config     = flag.String("config", "", "configuration `file`, overrides -root")
+	formatName = flag.String("format", "clf", "input `format`: clf, jsonl or csv")
+	uaField    = flag.String("ua-field", "user_agent", "JSON key or CSV column of the user agent, a column index for CSV without header")
+	header     = flag.Bool("header", true, "CSV input starts with a header line")
+	caps       = flag.String("caps", "", "comma separated `capabilities` to append")
+	vcaps      = flag.String("vcaps", "form_factor", "comma separated `virtual capabilities` to append")
+	workers    = flag.Int("workers", runtime.GOMAXPROCS(0), "number of parallel lookups")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: wurfl-enrich [flags] [file ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "wurfl-enrich: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func splitList(s string) []string {
+	var l []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			l = append(l, e)
+		}
+	}
+
+	return l
+}
+
+func open(cl, vl []string) (*gowurfl.WURFL, error) {
+	opts := []gowurfl.Option{
+		gowurfl.WithResultCache(gowurfl.NewResultCache(gowurfl.ResultCacheConfig{})),
+	}
+
+	if *config != "" {
+		c, err := configfile.Load(*config)
+		if err != nil {
+			return nil, err
+		}
+
+		c.Capabilities = append(c.Capabilities, cl...)
+		c.VirtualCapabilities = append(c.VirtualCapabilities, vl...)
+		return c.Open(opts...)
+	}
+
+	return gowurfl.Open(*root, append(opts, gowurfl.WithCapabilities(cl...), gowurfl.WithCapabilities(vl...))...)
+}
+
+func run(files []string) error {
+	cl, vl := splitList(*caps), splitList(*vcaps)
+
+	w, err := open(cl, vl)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	e := &enricher{w: w, caps: cl, vcaps: vl, workers: *workers}
+	opts := formatOptions{
+		uaField: *uaField,
+		columns: append(append([]string{"wurfl_id"}, cl...), vl...),
+		header:  *header,
+	}
+
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	for i, name := range files {
+		opts.skipHeader = i > 0
+
+		if err := e.file(name, os.Stdout, opts); err != nil {
+			return err
+		}
+	}
+
+	if e.failed > 0 {
+		fmt.Fprintf(os.Stderr, "wurfl-enrich: %d lookups failed\n", e.failed)
+	}
+
+	if e.malformed > 0 {
+		fmt.Fprintf(os.Stderr, "wurfl-enrich: %d malformed lines passed through unchanged\n", e.malformed)
+	}
+
+	return nil
+}
+
+type enricher struct {
+	w       *gowurfl.WURFL
+	caps    []string
+	vcaps   []string
+	workers int
+
+	mu        sync.Mutex
+	failed    int
+	malformed int
+}
+
+func (e *enricher) file(name string, out io.Writer, opts formatOptions) error {
+	in := io.Reader(os.Stdin)
+
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		in = f
+	}
+
+	f, err := newFormat(*formatName, in, out, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := e.run(f); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	return nil
+}
+
+// values looks up the user agent of r and returns the id followed by the
+// capabilities and virtual capabilities. Malformed records are counted and not
+// looked up. All values are empty if the lookup failed.
+func (e *enricher) values(r *record) []string {
+	if r.malformed {
+		e.mu.Lock()
+		e.malformed++
+		e.mu.Unlock()
+		return nil
+	}
+
+	ua := r.ua
+	vs := make([]string, 1+len(e.caps)+len(e.vcaps))
+
+	s, err := e.w.LookupSnapshot(ua)
+	if err != nil {
+		e.mu.Lock()
+		e.failed++
+		e.mu.Unlock()
+		return vs
+	}
+
+	vs[0], _ = s.GetID()
+	for i, c := range e.caps {
+		vs[1+i], _ = s.GetCapability(c)
+	}
+
+	for i, c := range e.vcaps {
+		vs[1+len(e.caps)+i], _ = s.GetVirtualCapability(c)
+	}
+
+	return vs
+}
+
+// run looks up the records of f on e.workers goroutines and writes them back in
+// their original order.
+func (e *enricher) run(f format) error {
+	type job struct {
+		r      *record
+		values []string
+		done   chan struct{}
+	}
+
+	workers := e.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	// queue keeps the jobs in input order and bounds the number in flight
+	queue := make(chan *job, 4*workers)
+	jobs := make(chan *job)
+
+	var readErr error
+	go func() {
+		defer close(queue)
+		defer close(jobs)
+
+		for {
+			r, err := f.Read()
+			if err != nil {
+				if err != io.EOF {
+					readErr = err
+				}
+				return
+			}
+
+			j := &job{r: r, done: make(chan struct{})}
+			queue <- j
+			jobs <- j
+		}
+	}()
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for j := range jobs {
+				j.values = e.values(j.r)
+				close(j.done)
+			}
+		}()
+	}
+
+	var writeErr error
+	for j := range queue {
+		<-j.done
+
+		if writeErr == nil {
+			writeErr = f.Write(j.r, j.values)
+		}
+	}
+
+	if writeErr != nil {
+		return writeErr
+	}
+
+	if err := f.Flush(); err != nil {
+		return err
+	}
+
+	return readErr
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/knarz/gowurfl"
+)
+
+var capsProfileCmd = &command{
+	name:  "caps-profile",
+	args:  "[-mandatory] <profile> ...",
+	short: "turn recorded capability profiles into the list to request",
+	run: func(c *command, e *env, args []string) error {
+		fs := c.flags()
+		mandatory := fs.Bool("mandatory", false, "keep the mandatory capabilities, which are always loaded")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		if fs.NArg() == 0 {
+			fs.Usage()
+			return flag.ErrHelp
+		}
+
+		p := &gowurfl.CapabilityProfile{}
+		for _, name := range fs.Args() {
+			f, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+
+			rp, err := gowurfl.ReadCapabilityProfile(f)
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+
+			p.Merge(rp)
+		}
+
+		w, err := e.open()
+		if err != nil {
+			return err
+		}
+
+		var skip []string
+		if !*mandatory {
+			if skip, err = w.GetMandatoryCapabilities(); err != nil {
+				return err
+			}
+		}
+
+		p, unknown := minimalProfile(p, w, skip)
+		for _, name := range unknown {
+			fmt.Fprintf(os.Stderr, "wurfl caps-profile: ignoring unknown capability %q\n", name)
+		}
+
+		if e.json {
+			return gowurfl.WriteCapabilityProfile(e.out, p)
+		}
+
+		return e.printList(append(p.Capabilities, p.VirtualCapabilities...))
+	},
+}
+
+// checker is implemented by *gowurfl.WURFL.
+type checker interface {
+	HasCapability(name string) bool
+	HasVirtualCapability(name string) bool
+}
+
+// minimalProfile drops the names in skip and the names c does not know from
+// p. The unknown names are returned as well.
+func minimalProfile(p *gowurfl.CapabilityProfile, c checker, skip []string) (*gowurfl.CapabilityProfile, []string) {
+	skipped := make(map[string]bool, len(skip))
+	for _, name := range skip {
+		skipped[name] = true
+	}
+
+	var unknown []string
+	mp := &gowurfl.CapabilityProfile{Capabilities: []string{}, VirtualCapabilities: []string{}}
+
+	for _, name := range p.Capabilities {
+		switch {
+		case !c.HasCapability(name):
+			unknown = append(unknown, name)
+		case !skipped[name]:
+			mp.Capabilities = append(mp.Capabilities, name)
+		}
+	}
+
+	for _, name := range p.VirtualCapabilities {
+		if !c.HasVirtualCapability(name) {
+			unknown = append(unknown, name)
+			continue
+		}
+		mp.VirtualCapabilities = append(mp.VirtualCapabilities, name)
+	}
+
+	return mp, unknown
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/knarz/gowurfl"
+)
+
+type fakeChecker map[string]bool
+
+func (c fakeChecker) HasCapability(name string) bool {
+	return c[name]
+}
+
+func (c fakeChecker) HasVirtualCapability(name string) bool {
+	return c["virtual:"+name]
+}
+
+func TestMinimalProfile(t *testing.T) {
+	c := fakeChecker{"brand_name": true, "model_name": true, "is_tablet": true, "virtual:is_mobile": true}
+	p := &gowurfl.CapabilityProfile{
+		Capabilities:        []string{"brand_name", "is_tablet", "modle_name"},
+		VirtualCapabilities: []string{"is_mobile", "is_ios"},
+	}
+
+	have, unknown := minimalProfile(p, c, []string{"is_tablet"})
+
+	want := &gowurfl.CapabilityProfile{
+		Capabilities:        []string{"brand_name"},
+		VirtualCapabilities: []string{"is_mobile"},
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("minimalProfile\nwant: %+v\nhave: %+v", want, have)
+	}
+
+	if !reflect.DeepEqual(unknown, []string{"modle_name", "is_ios"}) {
+		t.Errorf("unexpected unknown names %v", unknown)
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/knarz/gowurfl"
+)
+
+var lookupCmd = &command{
+	name:  "lookup",
+	args:  "[-caps list] [-vcaps list] <ua>",
+	short: "show the device detected for a user agent",
+	run: func(c *command, e *env, args []string) error {
+		fs := c.flags()
+		sel := selectionFlags(fs)
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return flag.ErrHelp
+		}
+
+		info, err := e.lookup(fs.Arg(0), sel)
+		if err != nil {
+			return err
+		}
+
+		return e.printDevice(info)
+	},
+}
+
+var deviceCmd = &command{
+	name:  "device",
+	args:  "[-caps list] [-vcaps list] <id>",
+	short: "show a device by its id",
+	run: func(c *command, e *env, args []string) error {
+		fs := c.flags()
+		sel := selectionFlags(fs)
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return flag.ErrHelp
+		}
+
+		w, err := e.open()
+		if err != nil {
+			return err
+		}
+
+		d, err := w.GetDevice(fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("%s: %w", fs.Arg(0), err)
+		}
+		defer d.Close()
+
+		info, err := describe(w, d, false, sel)
+		if err != nil {
+			return err
+		}
+
+		return e.printDevice(info)
+	},
+}
+
+var diffUACmd = &command{
+	name:  "diff-ua",
+	args:  "[-all] [-caps list] [-vcaps list] <ua1> <ua2>",
+	short: "compare the devices detected for two user agents",
+	run: func(c *command, e *env, args []string) error {
+		fs := c.flags()
+		sel := selectionFlags(fs)
+		all := fs.Bool("all", false, "also show values that are equal")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		if fs.NArg() != 2 {
+			fs.Usage()
+			return flag.ErrHelp
+		}
+
+		a, err := e.lookup(fs.Arg(0), sel)
+		if err != nil {
+			return err
+		}
+
+		b, err := e.lookup(fs.Arg(1), sel)
+		if err != nil {
+			return err
+		}
+
+		diffs := diffDevices(a, b, *all)
+
+		if e.json {
+			return printJSON(e.out, struct {
+				A           *deviceInfo  `json:"a"`
+				B           *deviceInfo  `json:"b"`
+				Differences []difference `json:"differences"`
+			}{a, b, diffs})
+		}
+
+		rows := [][]string{{"name", "ua1", "ua2"}}
+		for _, d := range diffs {
+			rows = append(rows, []string{d.Name, d.A, d.B})
+		}
+
+		return printTable(e.out, rows)
+	},
+}
+
+// selection decides which capabilities are shown. An empty list selects the
+// mandatory capabilities or all virtual capabilities, "all" selects all.
+type selection struct {
+	caps  *string
+	vcaps *string
+}
+
+func selectionFlags(fs *flag.FlagSet) selection {
+	return selection{
+		caps:  fs.String("caps", "", "comma separated capabilities to show, \"all\" for all, the mandatory ones by default"),
+		vcaps: fs.String("vcaps", "all", "comma separated virtual capabilities to show, \"all\" for all"),
+	}
+}
+
+func selectCaps(all gowurfl.Capabilities, list string, def []string) map[string]string {
+	if list == "all" {
+		return all
+	}
+
+	names := def
+	if list != "" {
+		names = splitList(list)
+	}
+
+	sel := make(map[string]string)
+	for _, n := range names {
+		if v, ok := all[n]; ok {
+			sel[n] = v
+		}
+	}
+
+	return sel
+}
+
+type deviceInfo struct {
+	ID                  string            `json:"id"`
+	RootID              string            `json:"root_id,omitempty"`
+	MatchType           string            `json:"match_type,omitempty"`
+	Matcher             string            `json:"matcher,omitempty"`
+	NormalizedUserAgent string            `json:"normalized_user_agent,omitempty"`
+	Fallback            []string          `json:"fallback"`
+	Capabilities        map[string]string `json:"capabilities"`
+	VirtualCapabilities map[string]string `json:"virtual_capabilities"`
+}
+
+func (e *env) lookup(ua string, sel selection) (*deviceInfo, error) {
+	w, err := e.open()
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := w.LookupUserAgent(ua)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	return describe(w, d, true, sel)
+}
+
+// describe collects what is known about d. The match information only makes
+// sense for devices that were looked up.
+func describe(w *gowurfl.WURFL, d *gowurfl.Device, match bool, sel selection) (*deviceInfo, error) {
+	id, err := d.GetID()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &deviceInfo{ID: id, RootID: d.GetRootID()}
+
+	if match {
+		info.MatchType = d.GetMatchType().String()
+		info.Matcher = d.GetMatcherName()
+		info.NormalizedUserAgent = d.GetNormalizedUserAgent()
+	}
+
+	if info.Fallback, err = w.GetFallbackChain(id); err != nil {
+		return nil, err
+	}
+
+	caps, err := d.GetCapabilities()
+	if err != nil {
+		return nil, err
+	}
+	info.Capabilities = selectCaps(caps, *sel.caps, gowurfl.MandatoryCapabilities)
+
+	vcaps, err := d.GetVirtualCapabilities()
+	if err != nil {
+		return nil, err
+	}
+	info.VirtualCapabilities = selectCaps(vcaps, *sel.vcaps, nil)
+
+	return info, nil
+}
+
+func (e *env) printDevice(info *deviceInfo) error {
+	if e.json {
+		return printJSON(e.out, info)
+	}
+
+	rows := [][]string{
+		{"field", "value"},
+		{"id", info.ID},
+		{"root id", info.RootID},
+	}
+
+	if info.MatchType != "" {
+		rows = append(rows,
+			[]string{"match type", info.MatchType},
+			[]string{"matcher", info.Matcher},
+			[]string{"normalized ua", info.NormalizedUserAgent},
+		)
+	}
+
+	rows = append(rows, []string{"fallback", strings.Join(info.Fallback, " > ")})
+
+	if err := printTable(e.out, rows); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(e.out)
+	if err := printTable(e.out, append([][]string{{"capability", "value"}}, mapRows(info.Capabilities)...)); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(e.out)
+	return printTable(e.out, append([][]string{{"virtual capability", "value"}}, mapRows(info.VirtualCapabilities)...))
+}
+
+type difference struct {
+	Name string `json:"name"`
+	A    string `json:"a"`
+	B    string `json:"b"`
+}
+
+// diffDevices compares the ids, match information and capabilities of two
+// devices. Virtual capabilities are prefixed with "virtual:".
+func diffDevices(a, b *deviceInfo, all bool) []difference {
+	var diffs []difference
+
+	add := func(name, va, vb string) {
+		if all || va != vb {
+			diffs = append(diffs, difference{name, va, vb})
+		}
+	}
+
+	add("id", a.ID, b.ID)
+	add("root id", a.RootID, b.RootID)
+	add("match type", a.MatchType, b.MatchType)
+	add("matcher", a.Matcher, b.Matcher)
+	add("fallback", strings.Join(a.Fallback, " > "), strings.Join(b.Fallback, " > "))
+
+	for _, n := range unionKeys(a.Capabilities, b.Capabilities) {
+		add(n, a.Capabilities[n], b.Capabilities[n])
+	}
+
+	for _, n := range unionKeys(a.VirtualCapabilities, b.VirtualCapabilities) {
+		add("virtual:"+n, a.VirtualCapabilities[n], b.VirtualCapabilities[n])
+	}
+
+	return diffs
+}
+
+func unionKeys(a, b map[string]string) []string {
+	seen := make(map[string]bool)
+	for k := range a {
+		seen[k] = true
+	}
+	for k := range b {
+		seen[k] = true
+	}
+
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffDevices(t *testing.T) {
+	a := &deviceInfo{
+		ID:                  "apple_iphone_ver9",
+		MatchType:           "exact",
+		Fallback:            []string{"apple_iphone_ver9", "generic"},
+		Capabilities:        map[string]string{"brand_name": "Apple", "is_tablet": "false"},
+		VirtualCapabilities: map[string]string{"form_factor": "Smartphone"},
+	}
+	b := &deviceInfo{
+		ID:                  "apple_ipad_ver1_sub9",
+		MatchType:           "exact",
+		Fallback:            []string{"apple_ipad_ver1_sub9", "generic"},
+		Capabilities:        map[string]string{"brand_name": "Apple", "is_tablet": "true"},
+		VirtualCapabilities: map[string]string{"form_factor": "Tablet", "is_ios": "true"},
+	}
+
+	want := []difference{
+		{"id", "apple_iphone_ver9", "apple_ipad_ver1_sub9"},
+		{"fallback", "apple_iphone_ver9 > generic", "apple_ipad_ver1_sub9 > generic"},
+		{"is_tablet", "false", "true"},
+		{"virtual:form_factor", "Smartphone", "Tablet"},
+		{"virtual:is_ios", "", "true"},
+	}
+
+	if d := diffDevices(a, b, false); !reflect.DeepEqual(d, want) {
+		t.Errorf("diffDevices()\nwant: %q\nhave: %q", want, d)
+	}
+
+	if d := diffDevices(a, b, true); len(d) != 9 {
+		t.Errorf("diffDevices() with all returned %d differences, want 9", len(d))
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+
+	"github.com/knarz/gowurfl"
+)
+
+var diffDataCmd = &command{
+	name:  "diff-data",
+	args:  "[-corpus file] [-vcaps list] <old.xml> <new.xml>",
+	short: "compare two root files and the detection of a corpus",
+	run: func(c *command, e *env, args []string) error {
+		fs := c.flags()
+		corpus := fs.String("corpus", "", "`file` of user agents, one per line, to compare the detection of")
+		vcaps := fs.String("vcaps", "form_factor", "comma separated virtual capabilities to compare for the corpus")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		if fs.NArg() != 2 {
+			fs.Usage()
+			return flag.ErrHelp
+		}
+
+		var opts gowurfl.DataDiffOptions
+		if *corpus != "" {
+			uas, err := readLines(*corpus)
+			if err != nil {
+				return err
+			}
+
+			opts.UserAgents = uas
+			opts.VirtualCapabilities = splitList(*vcaps)
+		}
+
+		from, err := e.openRoot(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer from.Close()
+
+		to, err := e.openRoot(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		defer to.Close()
+
+		dd, err := gowurfl.DiffData(from, to, opts)
+		if err != nil {
+			return err
+		}
+
+		if e.json {
+			return printJSON(e.out, dd)
+		}
+
+		return printTable(e.out, [][]string{
+			{"change", "count"},
+			{"added devices", strconv.Itoa(len(dd.AddedDevices))},
+			{"removed devices", strconv.Itoa(len(dd.RemovedDevices))},
+			{"added capabilities", strconv.Itoa(len(dd.AddedCapabilities))},
+			{"removed capabilities", strconv.Itoa(len(dd.RemovedCapabilities))},
+			{"changed parents", strconv.Itoa(len(dd.Parents))},
+			{"changed capability values", strconv.Itoa(len(dd.Capabilities))},
+			{"changed user agents", strconv.Itoa(len(dd.UserAgents))},
+		})
+	},
+}
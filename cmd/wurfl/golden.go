@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/knarz/gowurfl"
+)
+
+var goldenCmd = &command{
+	name:  "golden",
+	args:  "[-update] [-caps list] [-vcaps list] <golden.json> [corpus ...]",
+	short: "compare detection against a golden file, or record it",
+	run: func(c *command, e *env, args []string) error {
+		fs := c.flags()
+		update := fs.Bool("update", false, "record the golden file instead of comparing against it")
+		caps := fs.String("caps", "", "comma separated capabilities to record")
+		vcaps := fs.String("vcaps", "form_factor", "comma separated virtual capabilities to record")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		if fs.NArg() < 1 {
+			fs.Usage()
+			return flag.ErrHelp
+		}
+
+		name := fs.Arg(0)
+
+		if *update {
+			return e.recordGolden(name, fs.Args()[1:], splitList(*caps), splitList(*vcaps))
+		}
+
+		if fs.NArg() > 1 {
+			return fmt.Errorf("a corpus is only read with -update")
+		}
+
+		return e.checkGolden(name)
+	},
+}
+
+// recordGolden writes the golden file for the user agents of the corpus
+// files, or for those of the existing golden file if there are none.
+func (e *env) recordGolden(name string, corpus, caps, vcaps []string) error {
+	var uas []string
+
+	if len(corpus) == 0 {
+		golden, err := readGolden(name)
+		if err != nil {
+			return err
+		}
+
+		for _, g := range golden {
+			uas = append(uas, g.UserAgent)
+		}
+	}
+
+	for _, file := range corpus {
+		l, err := readLines(file)
+		if err != nil {
+			return err
+		}
+
+		uas = append(uas, l...)
+	}
+
+	w, err := e.open(gowurfl.WithCapabilities(caps...), gowurfl.WithCapabilities(vcaps...))
+	if err != nil {
+		return err
+	}
+
+	// user agents failing to look up are left out of the file and reported
+	golden, lerr := w.RecordGolden(uas, caps, vcaps)
+
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if err := gowurfl.WriteGolden(f, golden); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return lerr
+}
+
+func (e *env) checkGolden(name string) error {
+	golden, err := readGolden(name)
+	if err != nil {
+		return err
+	}
+
+	// the golden file decides which capabilities have to be loaded
+	seen := make(map[string]bool)
+	var caps []string
+	for _, g := range golden {
+		for _, m := range []map[string]string{g.Capabilities, g.VirtualCapabilities} {
+			for c := range m {
+				if !seen[c] {
+					seen[c] = true
+					caps = append(caps, c)
+				}
+			}
+		}
+	}
+
+	w, err := e.open(gowurfl.WithCapabilities(caps...))
+	if err != nil {
+		return err
+	}
+
+	diffs := w.CheckGolden(golden)
+
+	if e.json {
+		if err := printJSON(e.out, diffs); err != nil {
+			return err
+		}
+	} else if len(diffs) > 0 {
+		rows := [][]string{{"user agent", "field", "want", "have"}}
+		for _, d := range diffs {
+			rows = append(rows, []string{d.UserAgent, d.Field, d.Want, d.Have})
+		}
+
+		if err := printTable(e.out, rows); err != nil {
+			return err
+		}
+	}
+
+	if len(diffs) > 0 {
+		return fmt.Errorf("%d differences to %s", len(diffs), name)
+	}
+
+	return nil
+}
+
+func readGolden(name string) ([]gowurfl.Golden, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	golden, err := gowurfl.ReadGolden(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return golden, nil
+}
+
+// readLines returns the non empty lines of a file.
+func readLines(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for s.Scan() {
+		if l := strings.TrimSpace(s.Text()); l != "" {
+			lines = append(lines, l)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,183 @@
+// Command wurfl answers questions about user agents and the WURFL repository.
+//
+// Usage:
+//
+//	wurfl [-root file] [-config file] [-json] <command> [arguments]
+//
+// The commands are:
+//
+//	lookup <ua>             show the device detected for a user agent
+//	device <id>             show a device by its id
+//	caps                    list the loaded capabilities
+//	vcaps                   list the virtual capabilities
+//	info                    show information about the loaded repository
+//	diff-ua <ua1> <ua2>     compare the devices detected for two user agents
+//	report [file ...]       aggregate the devices of user agents read one per line
+//	golden <file>           compare detection against a golden file, or record it
+//	diff-data <old> <new>   compare two root files and the detection of a corpus
+//	validate                check capability values against their declared types
+//	caps-profile <file>...  turn recorded capability profiles into the list to request
+//	memory                  compare the memory taken by all, the mandatory and custom capabilities
+//
+// Run "wurfl <command> -h" for the flags of a command. With -json all commands
+// print JSON, which for diff-data is the only way to see the differences
+// themselves rather than a summary.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/knarz/gowurfl"
+	"github.com/knarz/gowurfl/configfile"
+)
+
+type command struct {
+	name  string
+	args  string
+	short string
+	run   func(c *command, e *env, args []string) error
+}
+
+var commands = []*command{
+	lookupCmd,
+	deviceCmd,
+	capsCmd,
+	vcapsCmd,
+	infoCmd,
+	diffUACmd,
+	reportCmd,
+	goldenCmd,
+	diffDataCmd,
+	validateCmd,
+	capsProfileCmd,
+	memoryCmd,
+}
+
+// env holds what the commands share.
+type env struct {
+	root   string
+	config string
+	json   bool
+	out    io.Writer
+
+	w *gowurfl.WURFL
+}
+
+// open loads the repository configured by the global flags. The engine is
+// opened once and shared by all subsequent calls.
+func (e *env) open(opts ...gowurfl.Option) (*gowurfl.WURFL, error) {
+	if e.w != nil {
+		return e.w, nil
+	}
+
+	var err error
+	if e.config != "" {
+		var c *gowurfl.Config
+		if c, err = configfile.Load(e.config); err != nil {
+			return nil, err
+		}
+		e.w, err = c.Open(opts...)
+	} else {
+		e.w, err = gowurfl.Open(e.root, opts...)
+	}
+
+	return e.w, err
+}
+
+// openRoot loads root with the settings of -config, if given, but not its
+// root. The engine is not shared.
+func (e *env) openRoot(root string) (*gowurfl.WURFL, error) {
+	if e.config == "" {
+		return gowurfl.Open(root)
+	}
+
+	c, err := configfile.Load(e.config)
+	if err != nil {
+		return nil, err
+	}
+	c.Root = root
+
+	return c.Open()
+}
+
+func (e *env) close() {
+	if e.w != nil {
+		e.w.Close()
+	}
+}
+
+// flags returns a FlagSet for the command that reports errors instead of
+// exiting.
+func (c *command) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: wurfl %s %s\n", c.name, c.args)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: wurfl [flags] <command> [arguments]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-30s %s\n", c.name+" "+c.args, c.short)
+	}
+	fmt.Fprintf(os.Stderr, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	e := &env{out: os.Stdout}
+
+	flag.StringVar(&e.root, "root", "/usr/share/wurfl/wurfl.xml", "WURFL root `file`")
+	flag.StringVar(&e.config, "config", "", "configuration `file`, overrides -root")
+	flag.BoolVar(&e.json, "json", false, "print JSON instead of tables")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := flag.Arg(0)
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+
+		err := c.run(c, e, flag.Args()[1:])
+		e.close()
+
+		if err == flag.ErrHelp {
+			os.Exit(2)
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "wurfl %s: %s\n", name, err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "wurfl: unknown command %q\n", name)
+	usage()
+	os.Exit(2)
+}
+
+func splitList(s string) []string {
+	var l []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			l = append(l, e)
+		}
+	}
+
+	return l
+}
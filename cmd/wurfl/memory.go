@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/knarz/gowurfl"
+	"github.com/knarz/gowurfl/configfile"
+)
+
+var memoryCmd = &command{
+	name:  "memory",
+	args:  "[-caps list] [-profile file]",
+	short: "compare the memory taken by all, the mandatory and custom capabilities",
+	run: func(c *command, e *env, args []string) error {
+		fs := c.flags()
+		caps := fs.String("caps", "", "comma separated capabilities to load as custom set")
+		profile := fs.String("profile", "", "capability profile `file` to load as custom set")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		if fs.NArg() != 0 {
+			fs.Usage()
+			return flag.ErrHelp
+		}
+
+		base := gowurfl.Config{Root: e.root}
+		if e.config != "" {
+			cfg, err := configfile.Load(e.config)
+			if err != nil {
+				return err
+			}
+			base = *cfg
+		}
+		base.Capabilities, base.VirtualCapabilities, base.CapabilityProfile = nil, nil, ""
+
+		mandatory := base
+		mandatory.Capabilities = gowurfl.MandatoryCapabilities
+
+		sets := []memorySet{{"full", base}, {"mandatory", mandatory}}
+
+		if *caps != "" || *profile != "" {
+			custom := base
+			custom.Capabilities = splitList(*caps)
+			custom.CapabilityProfile = *profile
+			sets = append(sets, memorySet{"custom", custom})
+		}
+
+		type usage struct {
+			Set        string `json:"set"`
+			Repository uint64 `json:"repository_bytes"`
+		}
+
+		var ul []usage
+		for _, s := range sets {
+			ms, err := loadMemoryStats(&s.cfg)
+			if err != nil {
+				return fmt.Errorf("%s: %w", s.name, err)
+			}
+			ul = append(ul, usage{s.name, ms.Repository})
+		}
+
+		if e.json {
+			return printJSON(e.out, ul)
+		}
+
+		rows := [][]string{{"set", "repository", "of full"}}
+		for _, u := range ul {
+			pct := "-"
+			if ul[0].Repository > 0 {
+				pct = fmt.Sprintf("%.1f%%", 100*float64(u.Repository)/float64(ul[0].Repository))
+			}
+			rows = append(rows, []string{u.Set, fmt.Sprintf("%.1f MiB", float64(u.Repository)/(1<<20)), pct})
+		}
+
+		return printTable(e.out, rows)
+	},
+}
+
+// memorySet is a configuration compared by the memory command.
+type memorySet struct {
+	name string
+	cfg  gowurfl.Config
+}
+
+// loadMemoryStats opens the configured engine, takes its memory stats and
+// closes it again.
+func loadMemoryStats(cfg *gowurfl.Config) (gowurfl.MemoryStats, error) {
+	w, err := cfg.Open()
+	if err != nil {
+		return gowurfl.MemoryStats{}, err
+	}
+	defer w.Close()
+
+	return w.MemoryStats()
+}
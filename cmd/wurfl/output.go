@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
+
+// printTable prints the rows as aligned columns. The first row is taken as the
+// header and underlined.
+func printTable(w io.Writer, rows [][]string) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	for i, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+
+		if i == 0 {
+			under := make([]string, len(row))
+			for j, h := range row {
+				under[j] = strings.Repeat("-", len(h))
+			}
+			fmt.Fprintln(tw, strings.Join(under, "\t"))
+		}
+	}
+
+	return tw.Flush()
+}
+
+// mapRows turns a map into rows sorted by key.
+func mapRows(m map[string]string) [][]string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	rows := make([][]string, len(keys))
+	for i, k := range keys {
+		rows[i] = []string{k, m[k]}
+	}
+
+	return rows
+}
+
+// printList prints one name per line, or a JSON array.
+func (e *env) printList(names []string) error {
+	sort.Strings(names)
+
+	if e.json {
+		return printJSON(e.out, names)
+	}
+
+	for _, n := range names {
+		if _, err := fmt.Fprintln(e.out, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/knarz/gowurfl"
+)
+
+var capsCmd = &command{
+	name:  "caps",
+	args:  "[-mandatory]",
+	short: "list the loaded capabilities",
+	run: func(c *command, e *env, args []string) error {
+		fs := c.flags()
+		mandatory := fs.Bool("mandatory", false, "only list the mandatory capabilities")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		if fs.NArg() != 0 {
+			fs.Usage()
+			return flag.ErrHelp
+		}
+
+		w, err := e.open()
+		if err != nil {
+			return err
+		}
+
+		var names []string
+		if *mandatory {
+			names, err = w.GetMandatoryCapabilities()
+		} else {
+			names, err = w.GetCapabilities()
+		}
+		if err != nil {
+			return err
+		}
+
+		return e.printList(names)
+	},
+}
+
+var vcapsCmd = &command{
+	name:  "vcaps",
+	short: "list the virtual capabilities",
+	run: func(c *command, e *env, args []string) error {
+		fs := c.flags()
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		if fs.NArg() != 0 {
+			fs.Usage()
+			return flag.ErrHelp
+		}
+
+		w, err := e.open()
+		if err != nil {
+			return err
+		}
+
+		names, err := w.GetVirtualCapabilities()
+		if err != nil {
+			return err
+		}
+
+		return e.printList(names)
+	},
+}
+
+type repoInfo struct {
+	Version       string    `json:"version"`
+	ReleaseDate   time.Time `json:"release_date,omitempty"`
+	Root          string    `json:"root"`
+	Patches       []string  `json:"patches"`
+	DeviceCount   int       `json:"device_count"`
+	APIVersion    string    `json:"api_version"`
+	EngineTarget  string    `json:"engine_target"`
+	CacheProvider string    `json:"cache_provider"`
+	CacheSizes    []int     `json:"cache_sizes"`
+	LoadTime      time.Time `json:"load_time"`
+}
+
+var infoCmd = &command{
+	name:  "info",
+	short: "show information about the loaded repository",
+	run: func(c *command, e *env, args []string) error {
+		fs := c.flags()
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		if fs.NArg() != 0 {
+			fs.Usage()
+			return flag.ErrHelp
+		}
+
+		w, err := e.open()
+		if err != nil {
+			return err
+		}
+
+		di, err := w.GetDataInfo()
+		if err != nil {
+			return err
+		}
+
+		cp, sizes := w.GetCacheProvider()
+		info := repoInfo{
+			Version:       di.Version,
+			ReleaseDate:   di.ReleaseDate,
+			Root:          di.Root,
+			Patches:       di.Patches,
+			DeviceCount:   di.DeviceCount,
+			APIVersion:    gowurfl.APIVersion(),
+			EngineTarget:  w.GetEngineTarget().String(),
+			CacheProvider: cp.String(),
+			CacheSizes:    sizes,
+			LoadTime:      w.LastLoadTime(),
+		}
+
+		if e.json {
+			return printJSON(e.out, info)
+		}
+
+		sl := make([]string, len(sizes))
+		for i, s := range sizes {
+			sl[i] = strconv.Itoa(s)
+		}
+
+		release := ""
+		if !info.ReleaseDate.IsZero() {
+			release = info.ReleaseDate.Format(time.DateOnly)
+		}
+
+		return printTable(e.out, [][]string{
+			{"field", "value"},
+			{"version", info.Version},
+			{"release date", release},
+			{"root", info.Root},
+			{"patches", strings.Join(info.Patches, ", ")},
+			{"devices", strconv.Itoa(info.DeviceCount)},
+			{"api version", info.APIVersion},
+			{"engine target", info.EngineTarget},
+			{"cache provider", info.CacheProvider + " " + strings.Join(sl, ",")},
+			{"loaded", info.LoadTime.Format(time.RFC3339)},
+		})
+	},
+}
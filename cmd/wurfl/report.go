@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/knarz/gowurfl"
+	"github.com/knarz/gowurfl/report"
+)
+
+var reportCmd = &command{
+	name:  "report",
+	args:  "[-caps list] [-vcaps list] [-top n] [-unique] [-format f] [file ...]",
+	short: "aggregate the devices of user agents read one per line",
+	run: func(c *command, e *env, args []string) error {
+		fs := c.flags()
+		caps := fs.String("caps", "", "comma separated capabilities to aggregate")
+		vcaps := fs.String("vcaps", "form_factor", "comma separated virtual capabilities to aggregate")
+		top := fs.Int("top", 10, "show the `n` most frequent values of each capability, 0 for all")
+		unique := fs.Bool("unique", false, "count every distinct user agent once")
+		format := fs.String("format", "markdown", "output `format`: markdown, csv or json")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		if e.json {
+			*format = "json"
+		}
+
+		write := map[string]func(*report.Report) error{
+			"markdown": func(r *report.Report) error { return r.WriteMarkdown(e.out) },
+			"csv":      func(r *report.Report) error { return r.WriteCSV(e.out) },
+			"json":     func(r *report.Report) error { return r.WriteJSON(e.out) },
+		}[*format]
+		if write == nil {
+			return fmt.Errorf("unknown format %q", *format)
+		}
+
+		cl, vl := splitList(*caps), splitList(*vcaps)
+
+		w, err := e.open(gowurfl.WithCapabilities(cl...), gowurfl.WithCapabilities(vl...))
+		if err != nil {
+			return err
+		}
+
+		a := report.New(w, report.Config{
+			Capabilities:        cl,
+			VirtualCapabilities: vl,
+			TopN:                *top,
+			Unique:              *unique,
+		})
+
+		files := fs.Args()
+		if len(files) == 0 {
+			if err := a.ReadLines(os.Stdin); err != nil {
+				return err
+			}
+		}
+
+		for _, name := range files {
+			f, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+
+			err = a.ReadLines(f)
+			f.Close()
+
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+
+		return write(a.Report())
+	},
+}
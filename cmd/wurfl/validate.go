@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+var validateCmd = &command{
+	name:  "validate",
+	short: "check capability values against their declared types",
+	run: func(c *command, e *env, args []string) error {
+		fs := c.flags()
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		if fs.NArg() != 0 {
+			fs.Usage()
+			return flag.ErrHelp
+		}
+
+		w, err := e.open()
+		if err != nil {
+			return err
+		}
+
+		found, err := w.ValidateCapabilities()
+		if err != nil {
+			return err
+		}
+
+		if e.json {
+			type finding struct {
+				DeviceID   string `json:"device_id"`
+				Capability string `json:"capability"`
+				Value      string `json:"value"`
+				Type       string `json:"type"`
+			}
+
+			fl := make([]finding, len(found))
+			for i, f := range found {
+				fl[i] = finding{f.DeviceID, f.Capability, f.Value, f.Type.String()}
+			}
+
+			if err := printJSON(e.out, fl); err != nil {
+				return err
+			}
+		} else if len(found) > 0 {
+			rows := [][]string{{"device", "capability", "value", "type"}}
+			for _, f := range found {
+				rows = append(rows, []string{f.DeviceID, f.Capability, f.Value, f.Type.String()})
+			}
+
+			if err := printTable(e.out, rows); err != nil {
+				return err
+			}
+		}
+
+		if len(found) > 0 {
+			return fmt.Errorf("%d invalid capability values", len(found))
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,320 @@
+package gowurfl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is a time.Duration that is written as a string such as "1h30m" in
+// configuration files.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Config describes an engine setup. It can be read from JSON files with
+// LoadConfig, from YAML and TOML files with the configfile package, and
+// overridden from the environment with FromEnv.
+// Names of engine targets, user agent priorities and cache providers are the
+// ones returned by their String methods, e.g. "high_performance",
+// "use_plain_useragent" or "double_lru". ValidateCapabilities enables
+// WithCapabilityValidation, CapabilityProfile names a file for
+// WithCapabilityProfile.
+type Config struct {
+	Root                 string   `json:"root" yaml:"root" toml:"root"`
+	Patches              []string `json:"patches" yaml:"patches" toml:"patches"`
+	EngineTarget         string   `json:"engine_target" yaml:"engine_target" toml:"engine_target"`
+	UserAgentPriority    string   `json:"useragent_priority" yaml:"useragent_priority" toml:"useragent_priority"`
+	CacheProvider        string   `json:"cache_provider" yaml:"cache_provider" toml:"cache_provider"`
+	CacheSizes           []int    `json:"cache_sizes" yaml:"cache_sizes" toml:"cache_sizes"`
+	Capabilities         []string `json:"capabilities" yaml:"capabilities" toml:"capabilities"`
+	VirtualCapabilities  []string `json:"virtual_capabilities" yaml:"virtual_capabilities" toml:"virtual_capabilities"`
+	ReloadInterval       Duration `json:"reload_interval" yaml:"reload_interval" toml:"reload_interval"`
+	ValidateCapabilities bool     `json:"validate_capabilities" yaml:"validate_capabilities" toml:"validate_capabilities"`
+	CapabilityProfile    string   `json:"capability_profile" yaml:"capability_profile" toml:"capability_profile"`
+}
+
+// LoadConfig reads the JSON configuration file at p and applies FromEnv on
+// top. YAML and TOML files are read by the configfile package, which keeps
+// their parsers out of programs that do not need them.
+func LoadConfig(p string) (*Config, error) {
+	if ext := strings.ToLower(filepath.Ext(p)); ext != ".json" {
+		return nil, fmt.Errorf("unsupported configuration format %q, use package configfile", ext)
+	}
+
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+
+	if err := c.FromEnv(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// FromEnv overrides the configuration with the WURFL_ROOT, WURFL_PATCHES,
+// WURFL_ENGINE_TARGET, WURFL_USERAGENT_PRIORITY, WURFL_CACHE_PROVIDER,
+// WURFL_CACHE_SIZES, WURFL_CAPABILITIES, WURFL_VIRTUAL_CAPABILITIES,
+// WURFL_RELOAD_INTERVAL, WURFL_VALIDATE_CAPABILITIES and
+// WURFL_CAPABILITY_PROFILE environment variables, as far as they are set.
+// Lists are comma separated.
+func (c *Config) FromEnv() error {
+	var errs []error
+
+	if v, ok := os.LookupEnv("WURFL_ROOT"); ok {
+		c.Root = v
+	}
+
+	if v, ok := os.LookupEnv("WURFL_PATCHES"); ok {
+		c.Patches = splitList(v)
+	}
+
+	if v, ok := os.LookupEnv("WURFL_ENGINE_TARGET"); ok {
+		c.EngineTarget = v
+	}
+
+	if v, ok := os.LookupEnv("WURFL_USERAGENT_PRIORITY"); ok {
+		c.UserAgentPriority = v
+	}
+
+	if v, ok := os.LookupEnv("WURFL_CACHE_PROVIDER"); ok {
+		c.CacheProvider = v
+	}
+
+	if v, ok := os.LookupEnv("WURFL_CACHE_SIZES"); ok {
+		c.CacheSizes = nil
+		for _, s := range splitList(v) {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("WURFL_CACHE_SIZES: %w", err))
+				continue
+			}
+			c.CacheSizes = append(c.CacheSizes, n)
+		}
+	}
+
+	if v, ok := os.LookupEnv("WURFL_CAPABILITIES"); ok {
+		c.Capabilities = splitList(v)
+	}
+
+	if v, ok := os.LookupEnv("WURFL_VIRTUAL_CAPABILITIES"); ok {
+		c.VirtualCapabilities = splitList(v)
+	}
+
+	if v, ok := os.LookupEnv("WURFL_RELOAD_INTERVAL"); ok {
+		if err := c.ReloadInterval.UnmarshalText([]byte(v)); err != nil {
+			errs = append(errs, fmt.Errorf("WURFL_RELOAD_INTERVAL: %w", err))
+		}
+	}
+
+	if v, ok := os.LookupEnv("WURFL_VALIDATE_CAPABILITIES"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("WURFL_VALIDATE_CAPABILITIES: %w", err))
+		}
+		c.ValidateCapabilities = b
+	}
+
+	if v, ok := os.LookupEnv("WURFL_CAPABILITY_PROFILE"); ok {
+		c.CapabilityProfile = v
+	}
+
+	return errors.Join(errs...)
+}
+
+func splitList(s string) []string {
+	var l []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			l = append(l, e)
+		}
+	}
+
+	return l
+}
+
+// ParseEngineTarget returns the engine target with the given name.
+func ParseEngineTarget(s string) (EngineTarget, error) {
+	for _, et := range []EngineTarget{EngineTargetHighAccuracy, EngineTargetHighPerformance} {
+		if et.String() == s {
+			return et, nil
+		}
+	}
+
+	return EngineTargetInvalid, fmt.Errorf("invalid engine target %q", s)
+}
+
+// ParseUserAgentPriority returns the user agent priority with the given name.
+func ParseUserAgentPriority(s string) (UserAgentPriority, error) {
+	for _, p := range []UserAgentPriority{UserAgentPriorityOverrideSideloadedBrowser, UserAgentPriorityUsePlainUserAgent} {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+
+	return UserAgentPriorityInvalid, fmt.Errorf("invalid user agent priority %q", s)
+}
+
+// ParseCacheProvider returns the cache provider with the given name.
+func ParseCacheProvider(s string) (CacheProvider, error) {
+	for _, c := range []CacheProvider{CacheProviderNone, CacheProviderLRU, CacheProviderDoubleLRU} {
+		if c.String() == s {
+			return c, nil
+		}
+	}
+
+	return CacheProviderNone, fmt.Errorf("invalid cache provider %q", s)
+}
+
+// options validates the configuration and turns it into options for Open. All
+// problems found are returned joined.
+func (c *Config) options() ([]Option, error) {
+	var (
+		opts []Option
+		errs []error
+	)
+
+	if c.Root == "" {
+		errs = append(errs, errors.New("no root file configured"))
+	}
+
+	if c.EngineTarget != "" {
+		et, err := ParseEngineTarget(c.EngineTarget)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		opts = append(opts, WithEngineTarget(et))
+	}
+
+	if c.UserAgentPriority != "" {
+		p, err := ParseUserAgentPriority(c.UserAgentPriority)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		opts = append(opts, WithUserAgentPriority(p))
+	}
+
+	if c.CacheProvider != "" {
+		cp, err := ParseCacheProvider(c.CacheProvider)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		opts = append(opts, WithCache(cp, c.CacheSizes...))
+	}
+
+	if c.ReloadInterval < 0 {
+		errs = append(errs, errors.New("negative reload interval"))
+	}
+
+	opts = append(opts,
+		WithPatches(c.Patches...),
+		WithCapabilities(c.Capabilities...),
+		WithCapabilities(c.VirtualCapabilities...),
+	)
+
+	if c.CapabilityProfile != "" {
+		opts = append(opts, WithCapabilityProfile(c.CapabilityProfile))
+	}
+
+	if c.ValidateCapabilities {
+		opts = append(opts, WithCapabilityValidation())
+	}
+
+	return opts, errors.Join(errs...)
+}
+
+// Open builds and loads the engine described by the configuration. opts are
+// applied after the configuration, e.g. to attach a logger.
+// libwurfl stops loading at the first unknown requested capability. In that
+// case the repository is loaded once more without restrictions to report all
+// unknown capabilities and virtual capabilities together.
+func (c *Config) Open(opts ...Option) (*WURFL, error) {
+	copts, err := c.options()
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := Open(c.Root, append(copts, opts...)...)
+	if err == nil {
+		return w, nil
+	}
+
+	for _, unknown := range []error{ErrorCapabilityNotFound, ErrorCantLoadCapabilityNotFound, ErrorVirtualCapabilityNotFound, ErrorCantLoadVirtualCapabilityNotFound} {
+		if errors.Is(err, unknown) {
+			if uerr := c.unknownCapabilities(); uerr != nil {
+				return nil, uerr
+			}
+			break
+		}
+	}
+
+	return nil, err
+}
+
+// unknownCapabilities loads the repository with all capabilities and returns
+// the configured names it does not know, joined. It returns nil if there are
+// none or if the repository cannot be loaded.
+func (c *Config) unknownCapabilities() error {
+	caps, vcaps := c.Capabilities, c.VirtualCapabilities
+	if c.CapabilityProfile != "" {
+		p, err := readCapabilityProfileFile(c.CapabilityProfile)
+		if err != nil {
+			return nil
+		}
+		caps = append(append([]string{}, caps...), p.Capabilities...)
+		vcaps = append(append([]string{}, vcaps...), p.VirtualCapabilities...)
+	}
+
+	w, err := Open(c.Root, WithPatches(c.Patches...))
+	if err != nil {
+		return nil
+	}
+	defer w.Close()
+
+	var errs []error
+	for _, cap := range caps {
+		if !w.HasCapability(cap) {
+			errs = append(errs, fmt.Errorf("%w: %q", ErrorCapabilityNotFound, cap))
+		}
+	}
+
+	for _, cap := range vcaps {
+		if !w.HasVirtualCapability(cap) {
+			errs = append(errs, fmt.Errorf("%w: %q", ErrorVirtualCapabilityNotFound, cap))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// NewReloader opens the configured engine and reloads it every ReloadInterval,
+// see Reloader.
+func (c *Config) NewReloader(opts ...Option) (*Reloader, error) {
+	return NewReloader(func() (*WURFL, error) {
+		return c.Open(opts...)
+	}, time.Duration(c.ReloadInterval))
+}
@@ -0,0 +1,285 @@
+package gowurfl
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testConfig = Config{
+	Root:           "/usr/share/wurfl/wurfl.xml",
+	Patches:        []string{"/etc/wurfl/patch.xml"},
+	EngineTarget:   "high_accuracy",
+	CacheProvider:  "lru",
+	CacheSizes:     []int{5000},
+	Capabilities:   []string{"brand_name", "model_name"},
+	ReloadInterval: Duration(time.Hour),
+}
+
+const testConfigJSON = `{
+	"root": "/usr/share/wurfl/wurfl.xml",
+	"patches": ["/etc/wurfl/patch.xml"],
+	"engine_target": "high_accuracy",
+	"cache_provider": "lru",
+	"cache_sizes": [5000],
+	"capabilities": ["brand_name", "model_name"],
+	"reload_interval": "1h"
+}`
+
+func TestLoadConfig(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "wurfl.json")
+	if err := os.WriteFile(p, []byte(testConfigJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := LoadConfig(p)
+	if err != nil {
+		t.Fatalf("LoadConfig(%q) failed with: %s", p, err)
+	}
+
+	if !reflect.DeepEqual(*c, testConfig) {
+		t.Errorf("LoadConfig(%q)\nwant: %+v\nhave: %+v", p, testConfig, *c)
+	}
+}
+
+func TestLoadConfigUnsupported(t *testing.T) {
+	for _, name := range []string{"wurfl.ini", "wurfl.yaml", "wurfl.toml"} {
+		p := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadConfig(p); err == nil {
+			t.Errorf("LoadConfig(%q) expected to fail but did not", p)
+		}
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("WURFL_ROOT", "/tmp/wurfl.xml")
+	t.Setenv("WURFL_CACHE_SIZES", "100, 10")
+	t.Setenv("WURFL_VIRTUAL_CAPABILITIES", "is_mobile,form_factor")
+	t.Setenv("WURFL_RELOAD_INTERVAL", "30m")
+	t.Setenv("WURFL_CAPABILITY_PROFILE", "/var/lib/wurfl/profile.json")
+
+	c := testConfig
+	if err := c.FromEnv(); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Root != "/tmp/wurfl.xml" {
+		t.Errorf("expected root to be overridden but got %q", c.Root)
+	}
+
+	if !reflect.DeepEqual(c.CacheSizes, []int{100, 10}) {
+		t.Errorf("expected cache sizes [100 10] but got %v", c.CacheSizes)
+	}
+
+	if !reflect.DeepEqual(c.VirtualCapabilities, []string{"is_mobile", "form_factor"}) {
+		t.Errorf("unexpected virtual capabilities %v", c.VirtualCapabilities)
+	}
+
+	if c.ReloadInterval != Duration(30*time.Minute) {
+		t.Errorf("expected reload interval 30m but got %v", time.Duration(c.ReloadInterval))
+	}
+
+	if c.CapabilityProfile != "/var/lib/wurfl/profile.json" {
+		t.Errorf("expected capability profile to be set but got %q", c.CapabilityProfile)
+	}
+
+	if c.EngineTarget != testConfig.EngineTarget {
+		t.Errorf("unset variables should not change the configuration")
+	}
+}
+
+func TestConfigFromEnvInvalid(t *testing.T) {
+	t.Setenv("WURFL_CACHE_SIZES", "many")
+	t.Setenv("WURFL_RELOAD_INTERVAL", "often")
+
+	var c Config
+	err := c.FromEnv()
+	if err == nil {
+		t.Fatalf("FromEnv() expected to fail but did not")
+	}
+
+	for _, v := range []string{"WURFL_CACHE_SIZES", "WURFL_RELOAD_INTERVAL"} {
+		if !strings.Contains(err.Error(), v) {
+			t.Errorf("error %q does not mention %s", err, v)
+		}
+	}
+}
+
+func TestConfigOptionsInvalid(t *testing.T) {
+	c := Config{EngineTarget: "fast", CacheProvider: "lfu", UserAgentPriority: "sideloaded"}
+
+	_, err := c.options()
+	if err == nil {
+		t.Fatalf("options() expected to fail but did not")
+	}
+
+	for _, s := range []string{"root", "fast", "lfu", "sideloaded"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error %q does not mention %q", err, s)
+		}
+	}
+}
+
+func TestParseNames(t *testing.T) {
+	for _, et := range []EngineTarget{EngineTargetHighAccuracy, EngineTargetHighPerformance} {
+		if p, err := ParseEngineTarget(et.String()); err != nil || p != et {
+			t.Errorf("ParseEngineTarget(%q) returned %v, %v", et, p, err)
+		}
+	}
+
+	for _, cp := range []CacheProvider{CacheProviderNone, CacheProviderLRU, CacheProviderDoubleLRU} {
+		if p, err := ParseCacheProvider(cp.String()); err != nil || p != cp {
+			t.Errorf("ParseCacheProvider(%q) returned %v, %v", cp, p, err)
+		}
+	}
+
+	for _, up := range []UserAgentPriority{UserAgentPriorityOverrideSideloadedBrowser, UserAgentPriorityUsePlainUserAgent} {
+		if p, err := ParseUserAgentPriority(up.String()); err != nil || p != up {
+			t.Errorf("ParseUserAgentPriority(%q) returned %v, %v", up, p, err)
+		}
+	}
+}
+
+func TestConfigOpen(t *testing.T) {
+	c := Config{
+		Root:                rootFile,
+		Capabilities:        []string{"brand_name"},
+		VirtualCapabilities: []string{"is_mobile"},
+	}
+
+	w, err := c.Open()
+	if err != nil {
+		t.Fatalf("Open() failed with: %s", err)
+	}
+	defer w.Close()
+
+	c.Capabilities = append(c.Capabilities, "no_such_capability", "another_capability")
+	c.VirtualCapabilities = append(c.VirtualCapabilities, "no_such_virtual_capability")
+
+	_, err = c.Open()
+	if !errors.Is(err, ErrorCapabilityNotFound) || !errors.Is(err, ErrorVirtualCapabilityNotFound) {
+		t.Fatalf("Open() with unknown capabilities returned %v", err)
+	}
+
+	for _, name := range []string{"no_such_capability", "another_capability", "no_such_virtual_capability"} {
+		if !strings.Contains(err.Error(), strconv.Quote(name)) {
+			t.Errorf("Open() did not report %q: %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"brand_name", "is_mobile"} {
+		if strings.Contains(err.Error(), strconv.Quote(name)) {
+			t.Errorf("Open() reported the known %q: %v", name, err)
+		}
+	}
+}
+
+func TestReloader(t *testing.T) {
+	defer func(d time.Duration) { ReloadGrace = d }(ReloadGrace)
+	ReloadGrace = 0
+
+	opened := 0
+	r, err := NewReloader(func() (*WURFL, error) {
+		opened++
+		return New()
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	first := r.Current()
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Current() == first || opened != 2 {
+		t.Errorf("Reload() did not replace the engine")
+	}
+
+	failed := errors.New("failed")
+	r.open = func() (*WURFL, error) { return nil, failed }
+
+	second := r.Current()
+	if err := r.Reload(); err != failed {
+		t.Errorf("Reload() expected %v but got %v", failed, err)
+	}
+
+	if r.Current() != second {
+		t.Errorf("a failed reload should keep the current engine")
+	}
+}
+
+func testClosed(w *WURFL) bool {
+	w.pool.mu.Lock()
+	defer w.pool.mu.Unlock()
+
+	return w.pool.closed
+}
+
+func TestReloaderAcquire(t *testing.T) {
+	defer func(d time.Duration) { ReloadGrace = d }(ReloadGrace)
+	ReloadGrace = 0
+
+	r, err := NewReloader(New, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, release := r.Acquire()
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if testClosed(first) {
+		t.Fatal("the replaced engine was closed while acquired")
+	}
+
+	release()
+	release()
+	if !testClosed(first) {
+		t.Error("the replaced engine was not closed once released")
+	}
+
+	second, release := r.Acquire()
+	r.Close()
+	if testClosed(second) {
+		t.Fatal("Close() closed the acquired current engine")
+	}
+
+	release()
+	if !testClosed(second) {
+		t.Error("the current engine was not closed once released after Close()")
+	}
+}
+
+func TestReloaderClosePending(t *testing.T) {
+	r, err := NewReloader(New, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := r.Current()
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if testClosed(first) {
+		t.Fatal("the replaced engine was closed before ReloadGrace passed")
+	}
+
+	r.Close()
+	if !testClosed(first) || !testClosed(r.Current()) {
+		t.Error("Close() left engines open")
+	}
+}
@@ -0,0 +1,48 @@
+// Package configfile reads a gowurfl.Config from JSON, YAML or TOML files. It
+// is kept apart from gowurfl, so programs reading JSON with
+// gowurfl.LoadConfig do not depend on the YAML and TOML parsers.
+package configfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/knarz/gowurfl"
+	"gopkg.in/yaml.v3"
+)
+
+// Load reads the configuration file at p, choosing the format by its extension
+// (.json, .yaml, .yml or .toml), and applies FromEnv on top.
+func Load(p string) (*gowurfl.Config, error) {
+	var unmarshal func([]byte, any) error
+
+	switch ext := strings.ToLower(filepath.Ext(p)); ext {
+	default:
+		return nil, fmt.Errorf("unsupported configuration format %q", ext)
+	case ".json":
+		return gowurfl.LoadConfig(p)
+	case ".yaml", ".yml":
+		unmarshal = yaml.Unmarshal
+	case ".toml":
+		unmarshal = toml.Unmarshal
+	}
+
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &gowurfl.Config{}
+	if err := unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+
+	if err := c.FromEnv(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
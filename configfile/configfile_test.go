@@ -0,0 +1,84 @@
+package configfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/knarz/gowurfl"
+)
+
+var testConfig = gowurfl.Config{
+	Root:           "/usr/share/wurfl/wurfl.xml",
+	Patches:        []string{"/etc/wurfl/patch.xml"},
+	EngineTarget:   "high_accuracy",
+	CacheProvider:  "lru",
+	CacheSizes:     []int{5000},
+	Capabilities:   []string{"brand_name", "model_name"},
+	ReloadInterval: gowurfl.Duration(time.Hour),
+}
+
+var testConfigFiles = map[string]string{
+	"wurfl.json": `{
+	"root": "/usr/share/wurfl/wurfl.xml",
+	"patches": ["/etc/wurfl/patch.xml"],
+	"engine_target": "high_accuracy",
+	"cache_provider": "lru",
+	"cache_sizes": [5000],
+	"capabilities": ["brand_name", "model_name"],
+	"reload_interval": "1h"
+}`,
+	"wurfl.yaml": `
+root: /usr/share/wurfl/wurfl.xml
+patches:
+  - /etc/wurfl/patch.xml
+engine_target: high_accuracy
+cache_provider: lru
+cache_sizes: [5000]
+capabilities: [brand_name, model_name]
+reload_interval: 1h
+`,
+	"wurfl.toml": `
+root = "/usr/share/wurfl/wurfl.xml"
+patches = ["/etc/wurfl/patch.xml"]
+engine_target = "high_accuracy"
+cache_provider = "lru"
+cache_sizes = [5000]
+capabilities = ["brand_name", "model_name"]
+reload_interval = "1h"
+`,
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, content := range testConfigFiles {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		c, err := Load(p)
+		if err != nil {
+			t.Errorf("Load(%q) failed with: %s", name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(*c, testConfig) {
+			t.Errorf("Load(%q)\nwant: %+v\nhave: %+v", name, testConfig, *c)
+		}
+	}
+}
+
+func TestLoadUnsupported(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "wurfl.ini")
+	if err := os.WriteFile(p, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(p); err == nil {
+		t.Errorf("Load(%q) expected to fail but did not", p)
+	}
+}
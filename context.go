@@ -0,0 +1,269 @@
+package gowurfl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// DefaultLookupWorkers is the number of workers serving the context aware
+// lookups unless SetLookupWorkers is called.
+var DefaultLookupWorkers = runtime.GOMAXPROCS(0)
+
+const loadProgressInterval = time.Second
+
+// ErrorClosed is returned by the context aware methods once the engine is
+// closed.
+var ErrorClosed = errors.New("engine is closed")
+
+// lookupPool is a bounded set of goroutines running the cgo calls on behalf of
+// the context aware methods, so the callers can stop waiting for them.
+type lookupPool struct {
+	mu      sync.Mutex
+	workers int
+	jobs    chan func()
+	done    chan struct{}
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// start starts the workers unless they are running already and returns the
+// channels to submit jobs on and to learn about stop. After stop it returns
+// ErrorClosed.
+func (p *lookupPool) start() (chan<- func(), <-chan struct{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, nil, ErrorClosed
+	}
+
+	if p.jobs == nil {
+		if p.workers <= 0 {
+			p.workers = DefaultLookupWorkers
+		}
+
+		p.jobs = make(chan func())
+		p.done = make(chan struct{})
+		p.wg.Add(p.workers)
+
+		for i := 0; i < p.workers; i++ {
+			go p.work(p.jobs, p.done)
+		}
+	}
+
+	return p.jobs, p.done, nil
+}
+
+func (p *lookupPool) work(jobs <-chan func(), done <-chan struct{}) {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case job := <-jobs:
+			job()
+		case <-done:
+			return
+		}
+	}
+}
+
+// stop makes further submissions fail with ErrorClosed and waits for the
+// running jobs to finish. It is safe to call concurrently with lookups and
+// more than once.
+func (p *lookupPool) stop() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+
+	p.closed = true
+	if p.done != nil {
+		close(p.done)
+	}
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
+
+// SetLookupWorkers sets the number of workers used by the context aware
+// lookups. It has to be called before the first of them.
+func (w *WURFL) SetLookupWorkers(n int) {
+	w.pool.mu.Lock()
+	w.pool.workers = n
+	w.pool.mu.Unlock()
+}
+
+type result[T any] struct {
+	v   T
+	err error
+}
+
+// runContext runs fn on the lookup pool and waits for it or for ctx to be
+// done, whatever comes first. If the caller gave up, release is called with
+// the value fn eventually produced.
+func runContext[T any](ctx context.Context, w *WURFL, fn func() (T, error), release func(T)) (T, error) {
+	var zero T
+
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	jobs, done, err := w.pool.start()
+	if err != nil {
+		return zero, err
+	}
+
+	res := make(chan result[T], 1)
+	job := func() {
+		if ctx.Err() != nil {
+			res <- result[T]{err: ctx.Err()}
+			return
+		}
+
+		v, err := fn()
+		res <- result[T]{v, err}
+	}
+
+	select {
+	case jobs <- job:
+	case <-done:
+		return zero, ErrorClosed
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+
+	select {
+	case r := <-res:
+		return r.v, r.err
+	case <-ctx.Done():
+		if release != nil {
+			go func() {
+				if r := <-res; r.err == nil {
+					release(r.v)
+				}
+			}()
+		}
+
+		return zero, ctx.Err()
+	}
+}
+
+// LookupUserAgentContext is like LookupUserAgent, but returns ctx.Err() as soon
+// as ctx is done. The lookups run on a pool of SetLookupWorkers goroutines,
+// waiting for a free worker is bounded by ctx as well.
+func (w *WURFL) LookupUserAgentContext(ctx context.Context, ua string) (*Device, error) {
+	return runContext(ctx, w, func() (*Device, error) {
+		return w.LookupUserAgent(ua)
+	}, (*Device).Close)
+}
+
+// LookupHeadersContext is like LookupHeaders, but returns ctx.Err() as soon as
+// ctx is done, see LookupUserAgentContext.
+func (w *WURFL) LookupHeadersContext(ctx context.Context, h http.Header) (*Device, error) {
+	return runContext(ctx, w, func() (*Device, error) {
+		return w.LookupHeaders(h)
+	}, (*Device).Close)
+}
+
+// LookupTrace is filled in by LookupSnapshotContext and LookupRequestSnapshot
+// if their context carries it, see WithLookupTrace.
+type LookupTrace struct {
+	// CacheHit is set if the snapshot was served from the ResultCache.
+	CacheHit bool
+}
+
+type lookupTraceKey struct{}
+
+// WithLookupTrace returns a copy of ctx carrying t, e.g. for instrumentation
+// to learn whether a snapshot lookup was served from the ResultCache.
+func WithLookupTrace(ctx context.Context, t *LookupTrace) context.Context {
+	return context.WithValue(ctx, lookupTraceKey{}, t)
+}
+
+func traceCacheHit(ctx context.Context, hit bool) {
+	if t, ok := ctx.Value(lookupTraceKey{}).(*LookupTrace); ok {
+		t.CacheHit = hit
+	}
+}
+
+// LookupSnapshotContext is like LookupSnapshot, but returns ctx.Err() as soon
+// as ctx is done. Cache hits are served without involving the lookup pool.
+func (w *WURFL) LookupSnapshotContext(ctx context.Context, ua string) (*Snapshot, error) {
+	key := normalizeUserAgent(ua)
+
+	s, ok := w.cachedSnapshot(key)
+	traceCacheHit(ctx, ok)
+	if ok {
+		return s, nil
+	}
+
+	return runContext(ctx, w, func() (*Snapshot, error) {
+		return w.lookupSnapshot(key, func() (*Device, error) { return w.LookupUserAgent(key) })
+	}, nil)
+}
+
+// LoadProgress is passed to the progress callback of LoadContext.
+type LoadProgress struct {
+	Root    string
+	Elapsed time.Duration
+	Done    bool
+	Err     error
+}
+
+// LoadContext is like Load, but can be abandoned through ctx. libwurfl offers
+// no way to interrupt loading, so it continues in the background; the engine
+// is unusable afterwards and only Close may be called, which releases the
+// handle once the load finished.
+// If progress is not nil it is called when loading starts, about once a second
+// while it is running and once it is done, unless it was abandoned.
+func (w *WURFL) LoadContext(ctx context.Context, progress func(LoadProgress)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if progress == nil {
+		progress = func(LoadProgress) {}
+	}
+
+	w.lifecycle.Lock()
+	w.loading = true
+	w.lifecycle.Unlock()
+
+	start := time.Now()
+	progress(LoadProgress{Root: w.path})
+
+	done := make(chan error, 1)
+	go func() {
+		err := w.Load()
+
+		w.lifecycle.Lock()
+		w.loading = false
+		if w.closePending {
+			w.destroy()
+		}
+		w.lifecycle.Unlock()
+
+		done <- err
+	}()
+
+	t := time.NewTicker(loadProgressInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			progress(LoadProgress{Root: w.path, Elapsed: time.Since(start), Done: true, Err: err})
+			return err
+		case <-t.C:
+			progress(LoadProgress{Root: w.path, Elapsed: time.Since(start)})
+		case <-ctx.Done():
+			w.log(w.levels.Load, "abandoned load", "root", w.path, "elapsed", time.Since(start), "error", ctx.Err())
+			return ctx.Err()
+		}
+	}
+}
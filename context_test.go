@@ -0,0 +1,142 @@
+package gowurfl
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLookupUserAgentContextCanceled(t *testing.T) {
+	w := testNewEngine(t)
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := w.LookupUserAgentContext(ctx, uas[0]); !errors.Is(err, context.Canceled) {
+		t.Errorf("LookupUserAgentContext() expected %v but got %v", context.Canceled, err)
+	}
+}
+
+func TestLookupUserAgentContextBusy(t *testing.T) {
+	w := testNewEngine(t)
+	defer w.Close()
+
+	w.SetLookupWorkers(1)
+	jobs, _, err := w.pool.start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// occupy the only worker
+	release := make(chan struct{})
+	jobs <- func() { <-release }
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := w.LookupUserAgentContext(ctx, uas[0]); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("LookupUserAgentContext() expected %v but got %v", context.DeadlineExceeded, err)
+	}
+}
+
+// TestLookupUserAgentContextClose closes the engine while context lookups are
+// running, run it with -race.
+func TestLookupUserAgentContextClose(t *testing.T) {
+	w := testNewEngine(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				d, err := w.LookupUserAgentContext(context.Background(), uas[0])
+				if errors.Is(err, ErrorClosed) {
+					return
+				}
+				if err == nil {
+					d.Close()
+				}
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	w.Close()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("context lookups did not return ErrorClosed after Close")
+	}
+
+	if _, err := w.LookupUserAgentContext(context.Background(), uas[0]); !errors.Is(err, ErrorClosed) {
+		t.Errorf("LookupUserAgentContext() after Close expected %v but got %v", ErrorClosed, err)
+	}
+}
+
+func TestLookupUserAgentContext(t *testing.T) {
+	w := testNewEngine(t)
+	defer w.Close()
+
+	var last LoadProgress
+	if err := w.SetRoot(rootFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.LoadContext(context.Background(), func(p LoadProgress) { last = p }); err != nil {
+		t.Fatal(err)
+	}
+
+	if !last.Done || last.Err != nil {
+		t.Errorf("LoadContext() did not report completion: %+v", last)
+	}
+
+	for _, ua := range uas {
+		d, err := w.LookupUserAgentContext(context.Background(), ua)
+		if err != nil {
+			t.Fatalf("LookupUserAgentContext(%q) failed with: %s", ua, err)
+		}
+		d.Close()
+	}
+}
+
+func TestLoadContextCanceled(t *testing.T) {
+	w := testNewEngine(t)
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := w.LoadContext(ctx, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("LoadContext() expected %v but got %v", context.Canceled, err)
+	}
+}
+
+func TestLookupSnapshotContextTrace(t *testing.T) {
+	c := NewResultCache(ResultCacheConfig{})
+	w := &WURFL{}
+	w.SetResultCache(c)
+	c.Add("Dillo/2.0", testSnapshot("dillo_ver2"))
+
+	var lt LookupTrace
+	s, err := w.LookupSnapshotContext(WithLookupTrace(context.Background(), &lt), "Dillo/2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.id != "dillo_ver2" || !lt.CacheHit {
+		t.Errorf("LookupSnapshotContext() returned %q with cache hit %v, want %q from the cache", s.id, lt.CacheHit, "dillo_ver2")
+	}
+}
@@ -0,0 +1,215 @@
+package gowurfl
+
+import "sort"
+
+// DataDiff describes how two repositories differ, usually an old and a new
+// version of the root file.
+type DataDiff struct {
+	// AddedDevices and RemovedDevices are the ids only present in the new
+	// or the old repository.
+	AddedDevices   []string `json:"added_devices"`
+	RemovedDevices []string `json:"removed_devices"`
+	// AddedCapabilities and RemovedCapabilities are the names of the loaded
+	// capabilities only present in one of the repositories.
+	AddedCapabilities   []string `json:"added_capabilities"`
+	RemovedCapabilities []string `json:"removed_capabilities"`
+	// Parents lists the devices present in both that fall back to another
+	// device now.
+	Parents []ParentChange `json:"parents"`
+	// Capabilities lists the changed values of capabilities loaded by both
+	// for devices present in both. Only values a device defines itself are
+	// compared: a value equal to the parent's in both repositories is
+	// skipped if the parent stayed the same, so a change in a parent shows up
+	// once, not for every device inheriting it.
+	Capabilities []CapabilityChange `json:"capabilities"`
+	// UserAgents lists how the detection of DataDiffOptions.UserAgents
+	// changed, Want being the old and Have the new result. User agents that
+	// cannot be looked up are listed with the Field "error".
+	UserAgents []GoldenDiff `json:"user_agents,omitempty"`
+}
+
+type ParentChange struct {
+	ID  string `json:"id"`
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+type CapabilityChange struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// DataDiffOptions configures the optional user agent part of DiffData.
+type DataDiffOptions struct {
+	// UserAgents are looked up in both repositories and compared by device
+	// id and VirtualCapabilities.
+	UserAgents          []string
+	VirtualCapabilities []string
+}
+
+// DiffData compares two loaded repositories device by device, from being the
+// old and to the new one. Both engines should load the same capabilities, only
+// those loaded by both are compared.
+func DiffData(from, to *WURFL, opts DataDiffOptions) (*DataDiff, error) {
+	dd := &DataDiff{}
+
+	oldIDs, err := from.GetDeviceIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	newIDs, err := to.GetDeviceIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	var common []string
+	dd.AddedDevices, dd.RemovedDevices, common = compareSets(oldIDs, newIDs)
+
+	oldCaps, err := from.GetCapabilities()
+	if err != nil {
+		return nil, err
+	}
+
+	newCaps, err := to.GetCapabilities()
+	if err != nil {
+		return nil, err
+	}
+
+	var caps []string
+	dd.AddedCapabilities, dd.RemovedCapabilities, caps = compareSets(oldCaps, newCaps)
+
+	for _, id := range common {
+		if err := dd.compareDevice(from, to, id, caps); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(opts.UserAgents) > 0 {
+		golden := from.recordGolden(opts.UserAgents, nil, opts.VirtualCapabilities, func(ua string, err error) {
+			dd.UserAgents = append(dd.UserAgents, GoldenDiff{UserAgent: ua, Field: "error", Want: err.Error()})
+		})
+
+		dd.UserAgents = append(dd.UserAgents, to.CheckGolden(golden)...)
+	}
+
+	return dd, nil
+}
+
+func (dd *DataDiff) compareDevice(from, to *WURFL, id string, caps []string) error {
+	od, err := from.GetDevice(id)
+	if err != nil {
+		return err
+	}
+	defer od.Close()
+
+	nd, err := to.GetDevice(id)
+	if err != nil {
+		return err
+	}
+	defer nd.Close()
+
+	op, np := od.GetParentID(), nd.GetParentID()
+	if op != np {
+		dd.Parents = append(dd.Parents, ParentChange{id, op, np})
+	}
+
+	ov, err := od.capabilities()
+	if err != nil {
+		return err
+	}
+
+	nv, err := nd.capabilities()
+	if err != nil {
+		return err
+	}
+
+	opv, err := from.parentCapabilities(od)
+	if err != nil {
+		return err
+	}
+
+	npv, err := to.parentCapabilities(nd)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range caps {
+		if !changed(c, ov, nv, opv, npv, op == np) {
+			continue
+		}
+
+		dd.Capabilities = append(dd.Capabilities, CapabilityChange{id, c, ov[c], nv[c]})
+	}
+
+	return nil
+}
+
+// parentCapabilities returns the capabilities of the parent of d, nil if d is
+// the root of the hierarchy.
+func (w *WURFL) parentCapabilities(d *Device) (Capabilities, error) {
+	parent := d.GetParentID()
+	if parent == "" || parent == "root" {
+		return nil, nil
+	}
+
+	p, err := w.GetDevice(parent)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Close()
+
+	return p.capabilities()
+}
+
+// changed reports whether the value of capability c differs between the
+// capabilities ov and nv of a device with the parent capabilities opv and npv.
+// A value inherited in both only changed in the parent, unless the device
+// falls back to another parent now, which makes the change its own.
+func changed(c string, ov, nv, opv, npv Capabilities, sameParent bool) bool {
+	if ov[c] == nv[c] {
+		return false
+	}
+
+	return !sameParent || !inherits(ov, opv, c) || !inherits(nv, npv, c)
+}
+
+// inherits reports whether the value of capability c in caps is the one of
+// the parent.
+func inherits(caps, parent Capabilities, c string) bool {
+	v, ok := parent[c]
+	return ok && v == caps[c]
+}
+
+// compareSets returns the sorted elements only in b, only in a and in both.
+func compareSets(a, b []string) (added, removed, common []string) {
+	inA := make(map[string]bool, len(a))
+	for _, s := range a {
+		inA[s] = true
+	}
+
+	inB := make(map[string]bool, len(b))
+	for _, s := range b {
+		inB[s] = true
+
+		if inA[s] {
+			common = append(common, s)
+		} else {
+			added = append(added, s)
+		}
+	}
+
+	for _, s := range a {
+		if !inB[s] {
+			removed = append(removed, s)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(common)
+
+	return added, removed, common
+}
@@ -0,0 +1,91 @@
+package gowurfl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareSets(t *testing.T) {
+	added, removed, common := compareSets(
+		[]string{"generic", "apple_iphone_ver1", "nokia_3310"},
+		[]string{"apple_iphone_ver2", "generic", "apple_iphone_ver1"},
+	)
+
+	if want := []string{"apple_iphone_ver2"}; !reflect.DeepEqual(added, want) {
+		t.Errorf("added: want %v, have %v", want, added)
+	}
+
+	if want := []string{"nokia_3310"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed: want %v, have %v", want, removed)
+	}
+
+	if want := []string{"apple_iphone_ver1", "generic"}; !reflect.DeepEqual(common, want) {
+		t.Errorf("common: want %v, have %v", want, common)
+	}
+}
+
+func TestDiffDataSame(t *testing.T) {
+	a := testNewEngine(t)
+	defer a.Close()
+	testLoadRepository(rootFile, a, t)
+
+	b := testNewEngine(t)
+	defer b.Close()
+	testLoadRepository(rootFile, b, t)
+
+	dd, err := DiffData(a, b, DataDiffOptions{UserAgents: uas, VirtualCapabilities: []string{"form_factor"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dd.AddedDevices)+len(dd.RemovedDevices)+len(dd.Parents)+len(dd.Capabilities)+len(dd.UserAgents) != 0 {
+		t.Errorf("DiffData() of the same root file is not empty: %+v", dd)
+	}
+}
+
+func TestInherits(t *testing.T) {
+	parent := Capabilities{"is_tablet": "false", "resolution_width": "90"}
+
+	for _, tc := range []struct {
+		caps Capabilities
+		name string
+		want bool
+	}{
+		{Capabilities{"is_tablet": "false"}, "is_tablet", true},
+		{Capabilities{"is_tablet": "true"}, "is_tablet", false},
+		{Capabilities{"brand_name": "Apple"}, "brand_name", false},
+		{Capabilities{"resolution_width": "90"}, "resolution_width", true},
+	} {
+		if have := inherits(tc.caps, parent, tc.name); have != tc.want {
+			t.Errorf("inherits(%v, %q): want %v, have %v", tc.caps, tc.name, tc.want, have)
+		}
+	}
+
+	if inherits(Capabilities{"is_tablet": "false"}, nil, "is_tablet") {
+		t.Error("inherits() without a parent is true")
+	}
+}
+
+func TestChanged(t *testing.T) {
+	ov := Capabilities{"is_tablet": "false"}
+	nv := Capabilities{"is_tablet": "true"}
+
+	for _, tc := range []struct {
+		opv, npv   Capabilities
+		sameParent bool
+		want       bool
+	}{
+		{Capabilities{"is_tablet": "false"}, Capabilities{"is_tablet": "true"}, true, false},
+		{Capabilities{"is_tablet": "false"}, Capabilities{"is_tablet": "true"}, false, true},
+		{Capabilities{"is_tablet": "false"}, Capabilities{"is_tablet": "false"}, true, true},
+		{nil, nil, true, true},
+	} {
+		if have := changed("is_tablet", ov, nv, tc.opv, tc.npv, tc.sameParent); have != tc.want {
+			t.Errorf("changed() with parents %v and %v, same parent %v: want %v, have %v", tc.opv, tc.npv, tc.sameParent, tc.want, have)
+		}
+	}
+
+	if changed("is_tablet", ov, ov, nil, nil, false) {
+		t.Error("changed() of the same value is true")
+	}
+}
@@ -0,0 +1,154 @@
+package gowurfl
+
+// #include <wurfl/wurfl.h>
+import "C"
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DataInfo is the parsed form of the string returned by GetInfo, complemented
+// with what the engine knows about the loaded repository.
+type DataInfo struct {
+	// Raw is the unparsed string as returned by GetInfo.
+	Raw string
+	// Version is the version description of the root file, e.g.
+	// "db.scientiamobile.com - 2016-05-11 09:35:11".
+	Version string
+	// ReleaseDate is the date found in Version, the zero time if there was
+	// none.
+	ReleaseDate time.Time
+	// APIVersion is the version of libwurfl.
+	APIVersion  string
+	Root        string
+	Patches     []string
+	DeviceCount int
+}
+
+var infoDateRe = regexp.MustCompile(`\d{4}-\d{2}-\d{2}(?: \d{2}:\d{2}:\d{2})?`)
+
+// parseInfo parses the output of wurfl_get_wurfl_info which is made up of
+// lines of the form "Root:<path>:<version>" and "Patch:<path>:<version>".
+// Anything else is taken as the version as a whole.
+func parseInfo(s string) DataInfo {
+	di := DataInfo{Raw: s}
+
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 || (parts[0] != "Root" && parts[0] != "Patch") {
+			if di.Version == "" {
+				di.Version = line
+			}
+			continue
+		}
+
+		if parts[0] == "Patch" {
+			di.Patches = append(di.Patches, parts[1])
+			continue
+		}
+
+		di.Root = parts[1]
+		di.Version = strings.TrimSpace(parts[2])
+	}
+
+	if loc := infoDateRe.FindStringIndex(di.Version); loc != nil {
+		// the version is followed by a differently formatted update time
+		di.Version = di.Version[:loc[1]]
+
+		date := di.Version[loc[0]:loc[1]]
+		layout := "2006-01-02 15:04:05"
+		if len(date) == len("2006-01-02") {
+			layout = "2006-01-02"
+		}
+
+		di.ReleaseDate, _ = time.Parse(layout, date)
+	}
+
+	return di
+}
+
+// GetDataInfo returns the parsed metadata of the loaded repository.
+func (w *WURFL) GetDataInfo() (*DataInfo, error) {
+	di, err := w.dataInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	di.DeviceCount = w.deviceCount()
+
+	return di, nil
+}
+
+// dataInfo is GetDataInfo without the costly device count.
+func (w *WURFL) dataInfo() (*DataInfo, error) {
+	s, err := w.GetInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	di := parseInfo(s)
+	di.APIVersion = APIVersion()
+
+	if di.Root == "" {
+		di.Root = w.path
+	}
+
+	if len(di.Patches) == 0 {
+		di.Patches = append([]string(nil), w.patches...)
+	}
+
+	return &di, nil
+}
+
+func (w *WURFL) deviceCount() int {
+	n := 0
+
+	enum := C.wurfl_get_device_enumerator(w.handle)
+	defer C.wurfl_device_enumerator_destroy(enum)
+
+	for C.wurfl_device_enumerator_is_valid(enum) == 1 {
+		n++
+		C.wurfl_device_enumerator_move_next(enum)
+	}
+
+	return n
+}
+
+// LastLoadTime returns the time the last successful Load finished, the zero
+// time if there was none.
+func (w *WURFL) LastLoadTime() time.Time {
+	w.lifecycle.Lock()
+	defer w.lifecycle.Unlock()
+
+	return w.loadTime
+}
+
+// DataAge returns the time passed since the release of the loaded root file.
+// If GetInfo does not reveal a release date, the modification time of the root
+// file is used instead.
+func (w *WURFL) DataAge() (time.Duration, error) {
+	di, err := w.dataInfo()
+	if err != nil {
+		return 0, err
+	}
+
+	if !di.ReleaseDate.IsZero() {
+		return time.Since(di.ReleaseDate), nil
+	}
+
+	fi, err := os.Stat(di.Root)
+	if err != nil {
+		return 0, errors.Join(errors.New("no release date in repository info"), err)
+	}
+
+	return time.Since(fi.ModTime()), nil
+}
@@ -0,0 +1,72 @@
+package gowurfl
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseInfo(t *testing.T) {
+	tcs := []struct {
+		in  string
+		out DataInfo
+	}{
+		{
+			"Root:/usr/share/wurfl/wurfl.xml:db.scientiamobile.com - 2016-05-11 09:35:11:Wed May 11 09:35:11 -0400 2016",
+			DataInfo{
+				Version:     "db.scientiamobile.com - 2016-05-11 09:35:11",
+				ReleaseDate: time.Date(2016, 5, 11, 9, 35, 11, 0, time.UTC),
+				Root:        "/usr/share/wurfl/wurfl.xml",
+			},
+		},
+		{
+			"Root:/usr/share/wurfl/wurfl.xml:for API 1.7.1.0, db.scientiamobile.com - 2016-05-11\nPatch:/etc/wurfl/patch.xml:custom",
+			DataInfo{
+				Version:     "for API 1.7.1.0, db.scientiamobile.com - 2016-05-11",
+				ReleaseDate: time.Date(2016, 5, 11, 0, 0, 0, 0, time.UTC),
+				Root:        "/usr/share/wurfl/wurfl.xml",
+				Patches:     []string{"/etc/wurfl/patch.xml"},
+			},
+		},
+		{
+			"custom data file",
+			DataInfo{Version: "custom data file"},
+		},
+	}
+
+	for _, tc := range tcs {
+		tc.out.Raw = tc.in
+
+		if di := parseInfo(tc.in); !reflect.DeepEqual(di, tc.out) {
+			t.Errorf("parseInfo(%q)\nwant: %+v\nhave: %+v", tc.in, tc.out, di)
+		}
+	}
+}
+
+func TestGetDataInfo(t *testing.T) {
+	w := testNewEngine(t)
+	defer w.Close()
+
+	if !w.LastLoadTime().IsZero() {
+		t.Errorf("LastLoadTime() should be zero before loading")
+	}
+
+	testLoadRepository(rootFile, w, t)
+
+	if time.Since(w.LastLoadTime()) > time.Minute {
+		t.Errorf("LastLoadTime() did not record the load: %v", w.LastLoadTime())
+	}
+
+	di, err := w.GetDataInfo()
+	if err != nil {
+		t.Fatalf("GetDataInfo() failed with: %s", err)
+	}
+
+	if di.Root != rootFile || di.DeviceCount == 0 || di.APIVersion == "" {
+		t.Errorf("unexpected data info: %+v", di)
+	}
+
+	if _, err := w.DataAge(); err != nil {
+		t.Errorf("DataAge() failed with: %s", err)
+	}
+}
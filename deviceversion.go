@@ -0,0 +1,149 @@
+package gowurfl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a version string as found in capabilities like
+// device_os_version, together with its leading numeric components. "10.3.1"
+// and "10_3_1" have the parts 10, 3 and 1, "4.0 beta" has 4 and 0 and "N/A"
+// none.
+type Version struct {
+	Raw   string
+	Parts []int
+}
+
+// ParseVersion parses the numbers separated by dots or underscores at the
+// start of s. Parsing stops at the first component that is not a number.
+func ParseVersion(s string) Version {
+	v := Version{Raw: s}
+
+	rest := strings.TrimSpace(s)
+	for rest != "" {
+		i := 0
+		for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+			i++
+		}
+
+		if i == 0 {
+			break
+		}
+
+		n, err := strconv.Atoi(rest[:i])
+		if err != nil {
+			break
+		}
+		v.Parts = append(v.Parts, n)
+
+		if i == len(rest) || (rest[i] != '.' && rest[i] != '_') {
+			break
+		}
+		rest = rest[i+1:]
+	}
+
+	return v
+}
+
+func (v Version) String() string {
+	return v.Raw
+}
+
+// Known reports whether the version has at least one numeric part. Unknown
+// versions are neither at least nor before any other version.
+func (v Version) Known() bool {
+	return len(v.Parts) > 0
+}
+
+// Compare returns -1, 0 or 1 if v is before, equal to or after o. Missing
+// parts count as 0, so "5" equals "5.0". Unknown versions compare as equal.
+func (v Version) Compare(o Version) int {
+	if !v.Known() || !o.Known() {
+		return 0
+	}
+
+	for i := 0; i < len(v.Parts) || i < len(o.Parts); i++ {
+		var a, b int
+		if i < len(v.Parts) {
+			a = v.Parts[i]
+		}
+		if i < len(o.Parts) {
+			b = o.Parts[i]
+		}
+
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// AtLeast reports whether v is the version s or a later one. It is false if
+// either version is unknown.
+func (v Version) AtLeast(s string) bool {
+	o := ParseVersion(s)
+	return v.Known() && o.Known() && v.Compare(o) >= 0
+}
+
+// Before reports whether v is earlier than the version s. It is false if
+// either version is unknown.
+func (v Version) Before(s string) bool {
+	o := ParseVersion(s)
+	return v.Known() && o.Known() && v.Compare(o) < 0
+}
+
+// OSVersion returns the parsed device_os_version capability.
+func (d *Device) OSVersion() (Version, error) {
+	return parsedVersion(d.GetCapability, "device_os_version")
+}
+
+// BrowserVersion returns the parsed mobile_browser_version capability.
+func (d *Device) BrowserVersion() (Version, error) {
+	return parsedVersion(d.GetCapability, "mobile_browser_version")
+}
+
+// AdvertisedOSVersion returns the parsed advertised_device_os_version virtual
+// capability, the version the user agent claims.
+func (d *Device) AdvertisedOSVersion() (Version, error) {
+	return parsedVersion(d.GetVirtualCapability, "advertised_device_os_version")
+}
+
+// AdvertisedBrowserVersion returns the parsed advertised_browser_version
+// virtual capability.
+func (d *Device) AdvertisedBrowserVersion() (Version, error) {
+	return parsedVersion(d.GetVirtualCapability, "advertised_browser_version")
+}
+
+// OSVersion is like Device.OSVersion.
+func (s *Snapshot) OSVersion() (Version, error) {
+	return parsedVersion(s.GetCapability, "device_os_version")
+}
+
+// BrowserVersion is like Device.BrowserVersion.
+func (s *Snapshot) BrowserVersion() (Version, error) {
+	return parsedVersion(s.GetCapability, "mobile_browser_version")
+}
+
+// AdvertisedOSVersion is like Device.AdvertisedOSVersion.
+func (s *Snapshot) AdvertisedOSVersion() (Version, error) {
+	return parsedVersion(s.GetVirtualCapability, "advertised_device_os_version")
+}
+
+// AdvertisedBrowserVersion is like Device.AdvertisedBrowserVersion.
+func (s *Snapshot) AdvertisedBrowserVersion() (Version, error) {
+	return parsedVersion(s.GetVirtualCapability, "advertised_browser_version")
+}
+
+func parsedVersion(get func(string) (string, error), name string) (Version, error) {
+	v, err := get(name)
+	if err != nil {
+		return Version{}, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return ParseVersion(v), nil
+}
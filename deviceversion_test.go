@@ -0,0 +1,91 @@
+package gowurfl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tcs := []struct {
+		in    string
+		parts []int
+	}{
+		{"10.3.1", []int{10, 3, 1}},
+		{"52.0.2715.0", []int{52, 0, 2715, 0}},
+		{"4.0 beta", []int{4, 0}},
+		{"9", []int{9}},
+		{"9.", []int{9}},
+		{"10_11_4", []int{10, 11, 4}},
+		{"N/A", nil},
+		{"", nil},
+	}
+
+	for _, tc := range tcs {
+		v := ParseVersion(tc.in)
+		if v.Raw != tc.in || !reflect.DeepEqual(v.Parts, tc.parts) {
+			t.Errorf("ParseVersion(%q) returned %+v, want parts %v", tc.in, v, tc.parts)
+		}
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	tcs := []struct {
+		v       string
+		o       string
+		compare int
+		atLeast bool
+		before  bool
+	}{
+		{"4.4.2", "5.0", -1, false, true},
+		{"5", "5.0", 0, true, false},
+		{"5.0.1", "5", 1, true, false},
+		{"10_11_4", "10.9", 1, true, false},
+		{"10.10", "10.9", 1, true, false},
+		{"N/A", "5.0", 0, false, false},
+		{"5.0", "", 0, false, false},
+	}
+
+	for _, tc := range tcs {
+		v := ParseVersion(tc.v)
+
+		if c := v.Compare(ParseVersion(tc.o)); c != tc.compare {
+			t.Errorf("%q.Compare(%q) returned %d, want %d", tc.v, tc.o, c, tc.compare)
+		}
+
+		if b := v.AtLeast(tc.o); b != tc.atLeast {
+			t.Errorf("%q.AtLeast(%q) returned %v, want %v", tc.v, tc.o, b, tc.atLeast)
+		}
+
+		if b := v.Before(tc.o); b != tc.before {
+			t.Errorf("%q.Before(%q) returned %v, want %v", tc.v, tc.o, b, tc.before)
+		}
+	}
+}
+
+func TestSnapshotVersions(t *testing.T) {
+	s := &Snapshot{
+		capabilities:        Capabilities{"device_os_version": "4.4.2", "mobile_browser_version": "49.0"},
+		virtualCapabilities: Capabilities{"advertised_device_os_version": "4.4.2", "advertised_browser_version": "49.0.2623.105"},
+	}
+
+	for name, f := range map[string]func() (Version, error){
+		"OSVersion":                s.OSVersion,
+		"BrowserVersion":           s.BrowserVersion,
+		"AdvertisedOSVersion":      s.AdvertisedOSVersion,
+		"AdvertisedBrowserVersion": s.AdvertisedBrowserVersion,
+	} {
+		v, err := f()
+		if err != nil {
+			t.Errorf("%s() failed with: %s", name, err)
+			continue
+		}
+
+		if !v.Known() || v.AtLeast("50") {
+			t.Errorf("%s() returned %+v", name, v)
+		}
+	}
+
+	if _, err := (&Snapshot{}).OSVersion(); err == nil {
+		t.Errorf("OSVersion() without device_os_version expected to fail but did not")
+	}
+}
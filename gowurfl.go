@@ -14,27 +14,65 @@ import "unsafe"
 
 import (
 	"errors"
+	"fmt"
+	"log/slog"
 	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 func New() (*WURFL, error) {
 	h := C.wurfl_handle(C.wurfl_create())
 	w := &WURFL{handle: h}
+	w.cacheStats.reset(CacheProviderDoubleLRU, defaultCacheProviderSizes)
+	w.levels = DefaultLogLevels
 
 	if err := w.CheckError(); err != nil {
 		return nil, err
 	}
 
+	if err := checkCompatibility(); err != nil {
+		w.Close()
+		return nil, err
+	}
+
 	return w, nil
 }
 
 type WURFL struct {
-	handle C.wurfl_handle
-	path   string
+	handle  C.wurfl_handle
+	path    string
+	patches []string
+	cache   *ResultCache
+	// cacheGen is the generation of cache entries belonging to the engine.
+	cacheGen atomic.Uint64
+
+	recorder *CapabilityRecorder
+
+	cacheStats cacheStats
+
+	logger *slog.Logger
+	levels LogLevels
+
+	pool lookupPool
+
+	importantHeaders     []string
+	importantHeadersOnce sync.Once
+
+	lifecycle    sync.Mutex
+	loading      bool
+	closePending bool
+	loadTime     time.Time
+
+	repositoryHeap uint64
 }
 
 type WURFLError error
 
+// ErrorLookupFailed is returned by the lookups if libwurfl returns no device.
+var ErrorLookupFailed = errors.New("failed to look up device")
+
 // s/_\(\w\)\([^_]\+\)/\1\L\2/g
 var (
 	ErrorInvalidHandle                     = errors.New("handle passed to the function is invalid")
@@ -112,6 +150,8 @@ func goError(e C.wurfl_error) error {
 	}
 }
 
+// ErrorCount returns 1 if libwurfl has an error message set and 0 otherwise.
+// libwurfl keeps only the last message.
 func (w *WURFL) ErrorCount() int {
 	return int(C.wurfl_has_error_message(w.handle))
 }
@@ -120,8 +160,8 @@ func (w *WURFL) ClearErrors() {
 	C.wurfl_clear_error_message(w.handle)
 }
 
-// LastError checks if libwurfl has any error messages queued
-// up and retrieves the last one if that is the case.
+// LastError retrieves the last error message of libwurfl without clearing it,
+// nil if there is none.
 func (w *WURFL) LastError() error {
 	err := C.wurfl_get_error_message(w.handle)
 	if err == nil {
@@ -136,13 +176,41 @@ func (w *WURFL) LastError() error {
 	return errors.New(es)
 }
 
-// CheckError is a convenience method that checks the error count,
-// retrieves it if > 0, clears them and then returns it.
+// DrainErrors returns the last error message of libwurfl, if there is one,
+// and clears it. libwurfl keeps only the last message, so at most one error is
+// returned.
+func (w *WURFL) DrainErrors() []error {
+	errs := w.takeErrors()
+
+	for _, err := range errs {
+		w.log(w.levels.Errors, "drained libwurfl error message", "error", err)
+	}
+
+	return errs
+}
+
+// takeErrors is DrainErrors without logging, for callers reporting the
+// message themselves.
+func (w *WURFL) takeErrors() []error {
+	if w.ErrorCount() == 0 {
+		return nil
+	}
+
+	err := w.LastError()
+	w.ClearErrors()
+
+	if err == nil {
+		return nil
+	}
+
+	return []error{err}
+}
+
+// CheckError is a convenience method that returns the last error message of
+// libwurfl and clears it, or nil if there is none.
 func (w *WURFL) CheckError() error {
 	if w.ErrorCount() > 0 {
-		err := w.LastError()
-		w.ClearErrors()
-		return err
+		return errors.Join(w.DrainErrors()...)
 	}
 
 	return nil
@@ -171,9 +239,21 @@ func (w *WURFL) SetEngineTarget(et EngineTarget) error {
 		return goError(err)
 	}
 
+	w.log(w.levels.Config, "set engine target", "target", et.String())
 	return nil
 }
 
+func (et EngineTarget) String() string {
+	switch et {
+	default:
+		return "invalid"
+	case EngineTargetHighAccuracy:
+		return "high_accuracy"
+	case EngineTargetHighPerformance:
+		return "high_performance"
+	}
+}
+
 type CacheProvider int
 
 const (
@@ -186,6 +266,8 @@ const (
 	defaultCacheProviderSize = "10000, 3000"
 )
 
+var defaultCacheProviderSizes = []int{10000, 3000}
+
 // SetCacheProvider sets the caching strategy to use.
 // From the documentation:
 //
@@ -201,7 +283,9 @@ const (
 // CacheProviderDoubleLRU uses the default if there are not enough size parameters.
 func (w *WURFL) SetCacheProvider(c CacheProvider, sizes ...int) error {
 	var cfg *C.char
-	defer C.free(unsafe.Pointer(cfg))
+	defer func() { C.free(unsafe.Pointer(cfg)) }()
+
+	var used []int
 
 	switch c {
 	default:
@@ -211,15 +295,19 @@ func (w *WURFL) SetCacheProvider(c CacheProvider, sizes ...int) error {
 	case CacheProviderLRU:
 		if len(sizes) >= 1 {
 			cfg = C.CString(strconv.Itoa(sizes[0]))
+			used = sizes[:1]
 		} else {
 			cfg = C.CString(defaultCacheProviderSize)
+			used = defaultCacheProviderSizes[:1]
 		}
 	case CacheProviderDoubleLRU:
 		if len(sizes) >= 2 {
 			s := strconv.Itoa(sizes[0]) + ", " + strconv.Itoa(sizes[1])
 			cfg = C.CString(s)
+			used = sizes[:2]
 		} else {
 			cfg = C.CString(defaultCacheProviderSize)
+			used = defaultCacheProviderSizes
 		}
 	}
 
@@ -228,9 +316,68 @@ func (w *WURFL) SetCacheProvider(c CacheProvider, sizes ...int) error {
 		return goError(err)
 	}
 
+	w.cacheStats.reset(c, used)
+	w.log(w.levels.Config, "set cache provider", "provider", c.String(), "sizes", used)
+
 	return nil
 }
 
+func (c CacheProvider) String() string {
+	switch c {
+	default:
+		return "invalid"
+	case CacheProviderNone:
+		return "none"
+	case CacheProviderLRU:
+		return "lru"
+	case CacheProviderDoubleLRU:
+		return "double_lru"
+	}
+}
+
+// UserAgentPriority decides which user agent is used for requests of
+// sideloaded browsers such as Opera Mini or UC Browser, which send the user
+// agent of the device in a side header like X-OperaMini-Phone-UA.
+type UserAgentPriority int
+
+const (
+	// UserAgentPriorityOverrideSideloadedBrowser detects the device from the
+	// side header if there is one. This is the default.
+	UserAgentPriorityOverrideSideloadedBrowser UserAgentPriority = C.WURFL_USERAGENT_PRIORITY_OVERRIDE_SIDELOADED_BROWSER_USERAGENT
+	// UserAgentPriorityUsePlainUserAgent always detects from User-Agent.
+	UserAgentPriorityUsePlainUserAgent UserAgentPriority = C.WURFL_USERAGENT_PRIORITY_USE_PLAIN_USERAGENT
+	UserAgentPriorityInvalid           UserAgentPriority = C.WURFL_USERAGENT_PRIORITY_INVALID
+)
+
+// GetUserAgentPriority retrieves the current user agent priority.
+func (w *WURFL) GetUserAgentPriority() UserAgentPriority {
+	return UserAgentPriority(C.wurfl_get_useragent_priority(w.handle))
+}
+
+// SetUserAgentPriority sets which user agent is used for sideloaded browsers.
+// It only affects lookups with headers, e.g. LookupRequest.
+func (w *WURFL) SetUserAgentPriority(p UserAgentPriority) error {
+	err := C.wurfl_error(C.wurfl_set_useragent_priority(w.handle, C.wurfl_useragent_priority(p)))
+
+	if err != C.WURFL_OK {
+		return goError(err)
+	}
+
+	w.log(w.levels.Config, "set user agent priority", "priority", p.String())
+	return nil
+}
+
+func (p UserAgentPriority) String() string {
+	switch p {
+	default:
+		return "invalid"
+	case UserAgentPriorityOverrideSideloadedBrowser:
+		return "override_sideloaded_browser"
+	case UserAgentPriorityUsePlainUserAgent:
+		return "use_plain_useragent"
+	}
+}
+
 func (w *WURFL) SetRoot(p string) error {
 	ps := C.CString(p)
 	defer C.free(unsafe.Pointer(ps))
@@ -242,9 +389,31 @@ func (w *WURFL) SetRoot(p string) error {
 	}
 
 	w.path = p
+	w.log(w.levels.Config, "set root", "root", p)
+	return nil
+}
+
+// AddPatch adds a patch file that is applied on top of the root file by Load.
+func (w *WURFL) AddPatch(p string) error {
+	ps := C.CString(p)
+	defer C.free(unsafe.Pointer(ps))
+
+	err := C.wurfl_add_patch(w.handle, ps)
+
+	if err != C.WURFL_OK {
+		return goError(err)
+	}
+
+	w.patches = append(w.patches, p)
+	w.log(w.levels.Config, "added patch", "patch", p)
 	return nil
 }
 
+// GetRoot returns the root file name set by SetRoot.
+func (w *WURFL) GetRoot() string {
+	return w.path
+}
+
 func (w *WURFL) GetInfo() (string, error) {
 	r := C.wurfl_get_wurfl_info(w.handle)
 
@@ -261,17 +430,75 @@ func (w *WURFL) GetInfo() (string, error) {
 // the root file name, and optionally AddPatch and/or AddRequestedCapability
 // to respectively specify patches and requested capabilities (if no capability
 // is requested, all capabilities from WURFL root file and patches are loaded).
+//
+// If a ResultCache is attached it is purged once loading succeeded, since the
+// snapshots it holds might stem from a different data file.
 func (w *WURFL) Load() error {
+	start := time.Now()
+	before, _ := heapInUse()
 	err := C.wurfl_error(C.wurfl_load(w.handle))
+	after, _ := heapInUse()
 
 	if err != C.WURFL_OK {
-		return goError(err)
+		lerr := w.withQueuedErrors(goError(err))
+		w.log(w.levels.Errors, "load failed", "root", w.path, "error", lerr)
+		return lerr
+	}
+
+	w.lifecycle.Lock()
+	w.loadTime = time.Now()
+	w.repositoryHeap = 0
+	if after > before {
+		w.repositoryHeap = after - before
+	}
+	w.lifecycle.Unlock()
+
+	if w.logger != nil {
+		args := []any{"root", w.path, "duration", time.Since(start), "heap_bytes", w.repositoryHeap}
+		if di, err := w.dataInfo(); err == nil {
+			args = append(args, "version", di.Version, "release_date", di.ReleaseDate,
+				"api_version", di.APIVersion, "patches", di.Patches)
+		}
+		w.log(w.levels.Load, "loaded repository", args...)
+	}
+
+	if w.cache != nil {
+		w.cacheGen.Store(w.cache.purge())
+		w.log(w.levels.Load, "purged result cache")
 	}
 
 	return nil
 }
 
+// withQueuedErrors joins err with the last error message of libwurfl, which
+// usually carries the details err is lacking. The message is not logged, the
+// caller logs the joined error.
+func (w *WURFL) withQueuedErrors(err error) error {
+	if w.ErrorCount() == 0 {
+		return err
+	}
+
+	return errors.Join(append([]error{err}, w.takeErrors()...)...)
+}
+
+// Close stops the lookup workers and releases the handle. If a load abandoned
+// through LoadContext is still running, the handle is released as soon as it
+// finished.
 func (w *WURFL) Close() {
+	w.pool.stop()
+
+	w.lifecycle.Lock()
+	defer w.lifecycle.Unlock()
+
+	if w.loading {
+		w.closePending = true
+		return
+	}
+
+	w.destroy()
+}
+
+func (w *WURFL) destroy() {
 	C.wurfl_destroy(w.handle)
 }
 
@@ -313,6 +540,7 @@ func (w *WURFL) AddRequestedCapability(cap string) error {
 		return goError(err)
 	}
 
+	w.log(w.levels.Config, "added requested capability", "capability", cap)
 	return nil
 }
 
@@ -337,6 +565,15 @@ func (w *WURFL) HasCapability(cap string) bool {
 	return int(ret) > 0
 }
 
+func (w *WURFL) HasVirtualCapability(cap string) bool {
+	cc := C.CString(cap)
+	defer C.free(unsafe.Pointer(cc))
+
+	ret := C.wurfl_has_virtual_capability(w.handle, cc)
+
+	return int(ret) > 0
+}
+
 func (w *WURFL) GetMandatoryCapabilities() ([]string, error) {
 	caps := []string{}
 
@@ -375,8 +612,29 @@ func (w *WURFL) GetCapabilities() ([]string, error) {
 	return caps, nil
 }
 
+// GetVirtualCapabilities returns the names of all virtual capabilities.
+func (w *WURFL) GetVirtualCapabilities() ([]string, error) {
+	caps := []string{}
+
+	enum := C.wurfl_get_virtual_capability_enumerator(w.handle)
+	defer C.wurfl_capability_enumerator_destroy(enum)
+
+	for C.wurfl_capability_enumerator_is_valid(enum) == 1 {
+		name := C.wurfl_capability_enumerator_get_name(enum)
+		if name == nil {
+			return caps, errors.New("failed to get name for capability enumerator")
+		}
+
+		caps = append(caps, C.GoString(name))
+		C.wurfl_capability_enumerator_move_next(enum)
+	}
+
+	return caps, nil
+}
+
 type Device struct {
-	handle C.wurfl_device_handle
+	handle   C.wurfl_device_handle
+	recorder *CapabilityRecorder
 }
 
 func (w *WURFL) LookupUserAgent(ua string) (*Device, error) {
@@ -385,10 +643,82 @@ func (w *WURFL) LookupUserAgent(ua string) (*Device, error) {
 	h := C.wurfl_lookup_useragent(w.handle, cua)
 
 	if h == nil {
-		return nil, errors.New("failed to look up user agent")
+		return nil, fmt.Errorf("user agent: %w", ErrorLookupFailed)
+	}
+
+	d := &Device{handle: h, recorder: w.recorder}
+	w.cacheStats.record(d.GetMatchType() == MatchTypeCached)
+
+	return d, nil
+}
+
+// GetDevice retrieves the device with the given id.
+func (w *WURFL) GetDevice(id string) (*Device, error) {
+	if id == "" {
+		return nil, ErrorEmptyID
+	}
+
+	cid := C.CString(id)
+	defer C.free(unsafe.Pointer(cid))
+	h := C.wurfl_get_device(w.handle, cid)
+
+	if h == nil {
+		return nil, ErrorDeviceNotFound
+	}
+
+	return &Device{handle: h, recorder: w.recorder}, nil
+}
+
+// GetDeviceIDs returns the ids of all devices in the repository.
+func (w *WURFL) GetDeviceIDs() ([]string, error) {
+	ids := []string{}
+
+	enum := C.wurfl_get_device_enumerator(w.handle)
+	defer C.wurfl_device_enumerator_destroy(enum)
+
+	for C.wurfl_device_enumerator_is_valid(enum) == 1 {
+		d := &Device{handle: C.wurfl_device_enumerator_get_device(enum), recorder: w.recorder}
+		if d.handle == nil {
+			return ids, errors.New("failed to get device for device enumerator")
+		}
+
+		id, err := d.GetID()
+		d.Close()
+
+		if err != nil {
+			return ids, err
+		}
+
+		ids = append(ids, id)
+		C.wurfl_device_enumerator_move_next(enum)
+	}
+
+	return ids, nil
+}
+
+// GetFallbackChain returns the ids of the device and all its ancestors, ending
+// with the generic device.
+func (w *WURFL) GetFallbackChain(id string) ([]string, error) {
+	var chain []string
+
+	for id != "" && id != "root" {
+		for _, c := range chain {
+			if c == id {
+				return chain, ErrorDeviceHierarchyCircularReference
+			}
+		}
+
+		d, err := w.GetDevice(id)
+		if err != nil {
+			return chain, err
+		}
+
+		chain = append(chain, id)
+		id = d.GetParentID()
+		d.Close()
 	}
 
-	return &Device{handle: h}, nil
+	return chain, nil
 }
 
 func (d *Device) GetID() (string, error) {
@@ -401,7 +731,78 @@ func (d *Device) GetID() (string, error) {
 	return C.GoString(id), nil
 }
 
+type MatchType int
+
+const (
+	MatchTypeExact           MatchType = C.WURFL_MATCH_TYPE_EXACT
+	MatchTypeConservative    MatchType = C.WURFL_MATCH_TYPE_CONSERVATIVE
+	MatchTypeRecovery        MatchType = C.WURFL_MATCH_TYPE_RECOVERY
+	MatchTypeCatchAll        MatchType = C.WURFL_MATCH_TYPE_CATCHALL
+	MatchTypeHighPerformance MatchType = C.WURFL_MATCH_TYPE_HIGHPERFORMANCE
+	MatchTypeNone            MatchType = C.WURFL_MATCH_TYPE_NONE
+	MatchTypeCached          MatchType = C.WURFL_MATCH_TYPE_CACHED
+)
+
+func (m MatchType) String() string {
+	switch m {
+	default:
+		return "unknown"
+	case MatchTypeExact:
+		return "exact"
+	case MatchTypeConservative:
+		return "conservative"
+	case MatchTypeRecovery:
+		return "recovery"
+	case MatchTypeCatchAll:
+		return "catchall"
+	case MatchTypeHighPerformance:
+		return "highperformance"
+	case MatchTypeNone:
+		return "none"
+	case MatchTypeCached:
+		return "cached"
+	}
+}
+
+// GetMatchType reports how libwurfl arrived at the device. MatchTypeCached
+// means the result was served from the cache set up by SetCacheProvider.
+func (d *Device) GetMatchType() MatchType {
+	return MatchType(C.wurfl_device_get_match_type(d.handle))
+}
+
+// GetParentID returns the id of the device this one falls back to, an empty
+// string for the generic device.
+func (d *Device) GetParentID() string {
+	return C.GoString(C.wurfl_device_get_parent_id(d.handle))
+}
+
+// GetRootID returns the id of the actual device root this device belongs to,
+// e.g. the plain model for a device identified by its firmware.
+func (d *Device) GetRootID() string {
+	return C.GoString(C.wurfl_device_get_root_id(d.handle))
+}
+
+// IsActualDeviceRoot reports whether the device represents an actual device
+// model rather than a group or firmware variant.
+func (d *Device) IsActualDeviceRoot() bool {
+	return C.wurfl_device_is_actual_device_root(d.handle) == 1
+}
+
+// GetMatcherName returns the name of the libwurfl matcher that identified the
+// device.
+func (d *Device) GetMatcherName() string {
+	return C.GoString(C.wurfl_device_get_matcher_name(d.handle))
+}
+
+// GetNormalizedUserAgent returns the user agent after libwurfl normalized it
+// for matching.
+func (d *Device) GetNormalizedUserAgent() string {
+	return C.GoString(C.wurfl_device_get_normalized_useragent(d.handle))
+}
+
 func (d *Device) HasVirtualCapability(cap string) (bool, error) {
+	d.recorder.virtualCapability(cap)
+
 	cc := C.CString(cap)
 	defer C.free(unsafe.Pointer(cc))
 	c := int(C.wurfl_device_has_virtual_capability(d.handle, cc))
@@ -415,6 +816,8 @@ func (d *Device) HasVirtualCapability(cap string) (bool, error) {
 
 //
 func (d *Device) GetVirtualCapability(cap string) (string, error) {
+	d.recorder.virtualCapability(cap)
+
 	cc := C.CString(cap)
 	defer C.free(unsafe.Pointer(cc))
 	c := C.wurfl_device_get_virtual_capability(d.handle, cc)
@@ -426,7 +829,19 @@ func (d *Device) GetVirtualCapability(cap string) (string, error) {
 	return C.GoString(c), nil
 }
 
+// GetVirtualCapabilities retrieves all virtual capabilities of the device. All
+// of them are recorded as used.
 func (d *Device) GetVirtualCapabilities() (Capabilities, error) {
+	caps, err := d.virtualCapabilities()
+	for name := range caps {
+		d.recorder.virtualCapability(name)
+	}
+
+	return caps, err
+}
+
+// virtualCapabilities is GetVirtualCapabilities without recording.
+func (d *Device) virtualCapabilities() (Capabilities, error) {
 	caps := make(Capabilities)
 
 	enum := C.wurfl_device_get_virtual_capability_enumerator(d.handle)
@@ -442,7 +857,43 @@ func (d *Device) GetVirtualCapabilities() (Capabilities, error) {
 	return caps, nil
 }
 
+// GetCapabilities retrieves all loaded capabilities of the device. All of them
+// are recorded as used.
+func (d *Device) GetCapabilities() (Capabilities, error) {
+	caps, err := d.capabilities()
+	for name := range caps {
+		d.recorder.capability(name)
+	}
+
+	return caps, err
+}
+
+// capabilities is GetCapabilities without recording.
+func (d *Device) capabilities() (Capabilities, error) {
+	caps := make(Capabilities)
+
+	enum := C.wurfl_device_get_capability_enumerator(d.handle)
+	defer C.wurfl_device_capability_enumerator_destroy(enum)
+
+	for C.wurfl_device_capability_enumerator_is_valid(enum) == 1 {
+		name := C.wurfl_device_capability_enumerator_get_name(enum)
+		value := C.wurfl_device_capability_enumerator_get_value(enum)
+		caps[C.GoString(name)] = C.GoString(value)
+		C.wurfl_device_capability_enumerator_move_next(enum)
+	}
+
+	return caps, nil
+}
+
+// GetCapability retrieves the value of a capability. It is the same as
+// GetCapabilitiy, which is kept for compatibility.
+func (d *Device) GetCapability(name string) (string, error) {
+	return d.GetCapabilitiy(name)
+}
+
 func (d *Device) GetCapabilitiy(name string) (string, error) {
+	d.recorder.capability(name)
+
 	cc := C.CString(name)
 	defer C.free(unsafe.Pointer(cc))
 
@@ -1,6 +1,9 @@
 package gowurfl
 
 import (
+	"fmt"
+	"net/http"
+	"strconv"
 	"testing"
 )
 
@@ -106,6 +109,37 @@ func TestSetEngineTarget(t *testing.T) {
 	}
 }
 
+func TestSetUserAgentPriority(t *testing.T) {
+	tcs := []struct {
+		in   UserAgentPriority
+		out  UserAgentPriority
+		fail bool
+	}{
+		{UserAgentPriorityUsePlainUserAgent, UserAgentPriorityUsePlainUserAgent, false},
+		{UserAgentPriorityOverrideSideloadedBrowser, UserAgentPriorityOverrideSideloadedBrowser, false},
+		{UserAgentPriorityInvalid, UserAgentPriorityOverrideSideloadedBrowser, true},
+	}
+
+	for _, tc := range tcs {
+		w := testNewEngine(t)
+		defer w.Close()
+
+		err := w.SetUserAgentPriority(tc.in)
+		if (err == nil) == tc.fail {
+			t.Errorf("SetUserAgentPriority(%v) returned %v", tc.in, err)
+		}
+
+		if tc.fail && err != ErrorInvalidUseragentPriority {
+			t.Errorf("SetUserAgentPriority(%v) expected %v but got %v", tc.in, ErrorInvalidUseragentPriority, err)
+		}
+
+		p := w.GetUserAgentPriority()
+		if p != tc.out {
+			t.Errorf("GetUserAgentPriority() expected %v but got %v", tc.out, p)
+		}
+	}
+}
+
 func TestSetCacheProvider(t *testing.T) {
 	tcs := []struct {
 		in    CacheProvider
@@ -197,6 +231,81 @@ func TestLookupUserAgent(t *testing.T) {
 	}
 }
 
+// sideloaded are requests of browsers that send the user agent of the device
+// in a side header.
+var sideloaded = []struct {
+	header   string
+	browser  string
+	deviceUA string
+}{
+	{
+		"X-OperaMini-Phone-UA",
+		"Opera/9.80 (J2ME/MIDP; Opera Mini/9.80 (S60; SymbOS; Opera Mobi/23.348; U; en) Presto/2.5.25 Version/10.54",
+		"Mozilla/5.0 (Linux; Android 4.4.2; Lenovo S860 Build/KOT49H) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/49.0.2623.105 Mobile Safari/537.36",
+	},
+	{
+		"X-UCBrowser-Device-UA",
+		"UCWEB/2.0 (Java; U; MIDP-2.0; en-US; GT-S5233S) U2/1.0.0 UCBrowser/9.4.1.377 U2/1.0.0 Mobile UNTRUSTED/1.0",
+		"Mozilla/5.0 (Linux; U; Android 4.1.2; it-it; GT-I8190 Build/JZO54K) AppleWebKit/534.30 (KHTML, like Gecko) Version/4.0 Mobile Safari/534.30",
+	},
+	{
+		"Device-Stock-UA",
+		"Opera/9.80 (J2ME/MIDP; Opera Mini/9.80 (S60; SymbOS; Opera Mobi/23.348; U; en) Presto/2.5.25 Version/10.54",
+		"Mozilla/5.0 (Linux; U; Android 2.3.6; es-es; LG-E400 Build/GRK39F) AppleWebKit/533.1 (KHTML, like Gecko) Version/4.0 Mobile Safari/533.1",
+	},
+}
+
+func testLookupID(t *testing.T, w *WURFL, ua string) string {
+	d, err := w.LookupUserAgent(ua)
+	if err != nil {
+		t.Fatalf("LookupUserAgent(%q) failed with: %s", ua, err)
+	}
+	defer d.Close()
+
+	id, err := d.GetID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+func TestLookupHeaders(t *testing.T) {
+	for _, p := range []UserAgentPriority{UserAgentPriorityOverrideSideloadedBrowser, UserAgentPriorityUsePlainUserAgent} {
+		w := testNewEngine(t)
+		defer w.Close()
+
+		if err := w.SetUserAgentPriority(p); err != nil {
+			t.Fatal(err)
+		}
+		testLoadRepository(rootFile, w, t)
+
+		for _, tc := range sideloaded {
+			want := testLookupID(t, w, tc.deviceUA)
+			if p == UserAgentPriorityUsePlainUserAgent {
+				want = testLookupID(t, w, tc.browser)
+			}
+
+			r, _ := http.NewRequest("GET", "http://example.com/", nil)
+			r.Header.Set("User-Agent", tc.browser)
+			r.Header.Set(tc.header, tc.deviceUA)
+
+			d, err := w.LookupRequest(r)
+			if err != nil {
+				t.Errorf("LookupRequest() with %s failed with: %s", tc.header, err)
+				continue
+			}
+
+			id, err := d.GetID()
+			d.Close()
+
+			if err != nil || id != want {
+				t.Errorf("LookupRequest() with %s and priority %v returned %q, %v, want %q", tc.header, p, id, err, want)
+			}
+		}
+	}
+}
+
 func TestDeviceGetVirtualCapability(t *testing.T) {
 	w := testNewEngine(t)
 	defer w.Close()
@@ -368,3 +477,109 @@ func BenchmarkLookupWithMandatoryCapabilities(b *testing.B) {
 		}
 	}
 }
+
+func TestGetCacheProvider(t *testing.T) {
+	tcs := []struct {
+		in       CacheProvider
+		sizes    []int
+		provider CacheProvider
+		out      []int
+	}{
+		{CacheProviderNone, []int{}, CacheProviderNone, []int{}},
+		{CacheProviderLRU, []int{}, CacheProviderLRU, []int{10000}},
+		{CacheProviderLRU, []int{500}, CacheProviderLRU, []int{500}},
+		{CacheProviderDoubleLRU, []int{}, CacheProviderDoubleLRU, []int{10000, 3000}},
+		{CacheProviderDoubleLRU, []int{500, 100}, CacheProviderDoubleLRU, []int{500, 100}},
+	}
+
+	for _, tc := range tcs {
+		w := testNewEngine(t)
+		defer w.Close()
+
+		if p, sizes := w.GetCacheProvider(); p != CacheProviderDoubleLRU || len(sizes) != 2 {
+			t.Errorf("GetCacheProvider() expected the default provider but got %v, %v", p, sizes)
+		}
+
+		if err := w.SetCacheProvider(tc.in, tc.sizes...); err != nil {
+			t.Fatalf("SetCacheProvider(%v, %v) failed with: %s", tc.in, tc.sizes, err)
+		}
+
+		p, sizes := w.GetCacheProvider()
+		if p != tc.provider || fmt.Sprint(sizes) != fmt.Sprint(tc.out) {
+			t.Errorf("GetCacheProvider() expected %v, %v but got %v, %v", tc.provider, tc.out, p, sizes)
+		}
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	w := testNewEngine(t)
+	defer w.Close()
+
+	if err := w.SetCacheProvider(CacheProviderLRU, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	testLoadRepository(rootFile, w, t)
+
+	// the first round fills the cache, the second one is served from it
+	for i := 0; i < 2; i++ {
+		for _, ua := range uas[:10] {
+			d, err := w.LookupUserAgent(ua)
+			if err != nil {
+				t.Fatalf("LookupUserAgent(%q) failed with: %s", ua, err)
+			}
+			d.Close()
+		}
+	}
+
+	if st := w.CacheStats(); st.Hits != 10 || st.Misses != 10 {
+		t.Errorf("expected 10 hits and 10 misses but got %+v", st)
+	}
+}
+
+func TestDrainErrors(t *testing.T) {
+	w := testNewEngine(t)
+	defer w.Close()
+
+	if errs := w.DrainErrors(); len(errs) != 0 {
+		t.Errorf("DrainErrors() on a fresh handle returned %v", errs)
+	}
+
+	if err := w.SetRoot("/nonexistent/wurfl.xml"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Load(); err == nil {
+		t.Fatalf("load of a missing root file should fail")
+	}
+
+	if n := w.ErrorCount(); n != 0 {
+		t.Errorf("Load() left %d messages in the error queue", n)
+	}
+}
+
+func TestDrainErrorsLastMessage(t *testing.T) {
+	w := testNewEngine(t)
+	defer w.Close()
+
+	testLoadRepository(rootFile, w, t)
+
+	for i := 0; i < 2; i++ {
+		if _, err := w.GetDevice("gowurfl_no_such_device_" + strconv.Itoa(i)); err == nil {
+			t.Fatalf("GetDevice() of an unknown id should fail")
+		}
+	}
+
+	// libwurfl keeps the last message only, however many errors occurred
+	if errs := w.DrainErrors(); len(errs) > 1 {
+		t.Errorf("DrainErrors() returned %d errors: %v", len(errs), errs)
+	}
+
+	if n := w.ErrorCount(); n != 0 {
+		t.Errorf("DrainErrors() left the error message set")
+	}
+
+	if errs := w.DrainErrors(); len(errs) != 0 {
+		t.Errorf("DrainErrors() after draining returned %v", errs)
+	}
+}
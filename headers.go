@@ -0,0 +1,158 @@
+package gowurfl
+
+// #include <stdlib.h>
+// #include <wurfl/wurfl.h>
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unsafe"
+)
+
+// LookupHeaders looks up the device for a request given by its headers. Unlike
+// LookupUserAgent it takes side headers such as X-OperaMini-Phone-UA or
+// X-UCBrowser-Device-UA into account, subject to SetUserAgentPriority.
+// Only the ImportantHeaders are passed to libwurfl, others such as Cookie or
+// Authorization are left out. Multiple values of a header are joined with ", ".
+func (w *WURFL) LookupHeaders(h http.Header) (*Device, error) {
+	ih := C.wurfl_important_header_create(w.handle)
+	if ih == nil {
+		return nil, errors.New("failed to create important headers")
+	}
+	defer C.wurfl_important_header_destroy(ih)
+
+	for _, name := range w.importantHeaderNames() {
+		values := h.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+
+		if err := setImportantHeader(ih, name, strings.Join(values, ", ")); err != nil {
+			return nil, err
+		}
+	}
+
+	dh := C.wurfl_lookup_with_important_header(w.handle, ih)
+	if dh == nil {
+		return nil, fmt.Errorf("headers: %w", ErrorLookupFailed)
+	}
+
+	d := &Device{handle: dh, recorder: w.recorder}
+	w.cacheStats.record(d.GetMatchType() == MatchTypeCached)
+
+	return d, nil
+}
+
+// LookupRequest looks up the device for the headers of r, see LookupHeaders.
+func (w *WURFL) LookupRequest(r *http.Request) (*Device, error) {
+	return w.LookupHeaders(r.Header)
+}
+
+func setImportantHeader(ih C.wurfl_important_header_handle, name, value string) error {
+	cn := C.CString(name)
+	defer C.free(unsafe.Pointer(cn))
+
+	cv := C.CString(value)
+	defer C.free(unsafe.Pointer(cv))
+
+	if err := C.wurfl_important_header_set(ih, cn, cv); err != C.WURFL_OK {
+		return goError(err)
+	}
+
+	return nil
+}
+
+// ImportantHeaders returns the canonical names of the request headers libwurfl
+// considers for LookupHeaders, e.g. to build cache keys or a Vary header. The
+// list is the same for every engine of a libwurfl version and only enumerated
+// once.
+func (w *WURFL) ImportantHeaders() []string {
+	return append([]string(nil), w.importantHeaderNames()...)
+}
+
+// importantHeaderNames returns the important headers without copying them, the
+// result must not be modified.
+func (w *WURFL) importantHeaderNames() []string {
+	w.importantHeadersOnce.Do(func() {
+		enum := C.wurfl_get_important_header_enumerator(w.handle)
+		defer C.wurfl_important_header_enumerator_destroy(enum)
+
+		for C.wurfl_important_header_enumerator_is_valid(enum) == 1 {
+			name := C.wurfl_important_header_enumerator_get_value(enum)
+			if name != nil {
+				w.importantHeaders = append(w.importantHeaders, http.CanonicalHeaderKey(C.GoString(name)))
+			}
+			C.wurfl_important_header_enumerator_move_next(enum)
+		}
+
+		sort.Strings(w.importantHeaders)
+	})
+
+	return w.importantHeaders
+}
+
+// Vary returns the important headers as the value of a Vary response header.
+func (w *WURFL) Vary() string {
+	return strings.Join(w.importantHeaderNames(), ", ")
+}
+
+// RequestCacheKey returns a key identifying r as far as device detection is
+// concerned. It is made of the important headers only, so requests with the
+// same key are detected as the same device. Whitespace in the values is
+// normalized like for LookupSnapshot.
+func (w *WURFL) RequestCacheKey(r *http.Request) string {
+	return requestCacheKey(w.importantHeaderNames(), r.Header)
+}
+
+// normalizedHeaders returns the headers of h named in names with the values
+// normalized like for requestCacheKey.
+func normalizedHeaders(names []string, h http.Header) http.Header {
+	n := make(http.Header, len(names))
+
+	for _, name := range names {
+		if vs := h.Values(name); len(vs) > 0 {
+			n.Set(name, normalizeUserAgent(strings.Join(vs, ", ")))
+		}
+	}
+
+	return n
+}
+
+// requestCacheKey writes one "name: value" line per header in the order
+// given, missing headers with an empty value, so the key does not depend on
+// the order of the request headers.
+func requestCacheKey(names []string, h http.Header) string {
+	var b strings.Builder
+
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteString(": ")
+		b.WriteString(normalizeUserAgent(strings.Join(h.Values(name), ", ")))
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
+
+// LookupRequestSnapshot is like LookupRequest, but returns a Snapshot. If a
+// ResultCache is attached, it is consulted first using RequestCacheKey. libwurfl
+// is queried with the important headers normalized like in the key, so the
+// result does not depend on whether it was cached.
+func (w *WURFL) LookupRequestSnapshot(r *http.Request) (*Snapshot, error) {
+	names := w.importantHeaderNames()
+	key := requestCacheKey(names, r.Header)
+
+	s, ok := w.cachedSnapshot(key)
+	traceCacheHit(r.Context(), ok)
+	if ok {
+		return s, nil
+	}
+
+	return w.lookupSnapshot(key, func() (*Device, error) {
+		return w.LookupHeaders(normalizedHeaders(names, r.Header))
+	})
+}
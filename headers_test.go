@@ -0,0 +1,68 @@
+package gowurfl
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRequestCacheKey(t *testing.T) {
+	names := []string{"Device-Stock-Ua", "User-Agent", "X-Operamini-Phone-Ua"}
+
+	a := http.Header{}
+	a.Set("User-Agent", "Opera/9.80 (J2ME/MIDP;  Opera Mini/9.80)")
+	a.Set("X-OperaMini-Phone-UA", "Nokia6300/2.0")
+	a.Set("Accept", "text/html")
+
+	b := http.Header{}
+	b.Set("X-OperaMini-Phone-UA", "Nokia6300/2.0")
+	b.Set("User-Agent", "Opera/9.80 (J2ME/MIDP; Opera Mini/9.80)")
+	b.Set("Cookie", "session=1")
+
+	want := "Device-Stock-Ua: \nUser-Agent: Opera/9.80 (J2ME/MIDP; Opera Mini/9.80)\nX-Operamini-Phone-Ua: Nokia6300/2.0\n"
+
+	if k := requestCacheKey(names, a); k != want {
+		t.Errorf("requestCacheKey()\nwant: %q\nhave: %q", want, k)
+	}
+
+	if ka, kb := requestCacheKey(names, a), requestCacheKey(names, b); ka != kb {
+		t.Errorf("requestCacheKey() differs for requests with the same important headers\n%q\n%q", ka, kb)
+	}
+
+	b.Set("Device-Stock-UA", "Nokia6300/2.0")
+	if ka, kb := requestCacheKey(names, a), requestCacheKey(names, b); ka == kb {
+		t.Errorf("requestCacheKey() is the same for requests with different important headers: %q", ka)
+	}
+}
+
+func TestNormalizedHeaders(t *testing.T) {
+	names := []string{"Device-Stock-Ua", "User-Agent"}
+
+	h := http.Header{}
+	h.Add("User-Agent", "  Opera/9.80 (J2ME/MIDP;  Opera Mini/9.80)")
+	h.Add("User-Agent", "Nokia6300/2.0 ")
+	h.Set("Accept", "text/html")
+
+	want := http.Header{"User-Agent": {"Opera/9.80 (J2ME/MIDP; Opera Mini/9.80), Nokia6300/2.0"}}
+	if n := normalizedHeaders(names, h); !reflect.DeepEqual(n, want) {
+		t.Errorf("normalizedHeaders()\nwant: %v\nhave: %v", want, n)
+	}
+}
+
+func TestImportantHeaders(t *testing.T) {
+	w := testNewEngine(t)
+	defer w.Close()
+
+	headers := w.ImportantHeaders()
+
+	found := false
+	for _, h := range headers {
+		if h == "User-Agent" {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("ImportantHeaders() does not contain User-Agent: %v", headers)
+	}
+}
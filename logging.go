@@ -0,0 +1,45 @@
+package gowurfl
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogLevels sets the level each kind of event is logged at.
+type LogLevels struct {
+	// Config is used for configuration changes such as SetEngineTarget.
+	Config slog.Level
+	// Load is used for successful loads and cache invalidation.
+	Load slog.Level
+	// Errors is used for failed loads and error messages drained from
+	// libwurfl.
+	Errors slog.Level
+	// Reload is used by the Reloader when it swaps engines.
+	Reload slog.Level
+}
+
+// DefaultLogLevels are the levels used unless SetLogLevels is called.
+var DefaultLogLevels = LogLevels{
+	Config: slog.LevelDebug,
+	Load:   slog.LevelInfo,
+	Errors: slog.LevelWarn,
+	Reload: slog.LevelInfo,
+}
+
+// SetLogger attaches a logger to the engine. Nothing is logged without one.
+func (w *WURFL) SetLogger(l *slog.Logger) {
+	w.logger = l
+}
+
+// SetLogLevels changes the levels events are logged at.
+func (w *WURFL) SetLogLevels(lv LogLevels) {
+	w.levels = lv
+}
+
+func (w *WURFL) log(level slog.Level, msg string, args ...any) {
+	if w.logger == nil {
+		return
+	}
+
+	w.logger.Log(context.Background(), level, msg, args...)
+}
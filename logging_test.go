@@ -0,0 +1,115 @@
+package gowurfl
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func testLogRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var recs []map[string]any
+
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		rec := make(map[string]any)
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatal(err)
+		}
+
+		recs = append(recs, rec)
+	}
+
+	return recs
+}
+
+func TestSetLogger(t *testing.T) {
+	w := testNewEngine(t)
+	defer w.Close()
+
+	var buf bytes.Buffer
+	w.SetLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	if err := w.SetEngineTarget(EngineTargetHighAccuracy); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.SetCacheProvider(CacheProviderLRU, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	recs := testLogRecords(t, &buf)
+	if len(recs) != 2 {
+		t.Fatalf("expected 2 log records but got %d", len(recs))
+	}
+
+	if recs[0]["target"] != "high_accuracy" || recs[0]["level"] != "DEBUG" {
+		t.Errorf("unexpected record for SetEngineTarget: %v", recs[0])
+	}
+
+	if recs[1]["provider"] != "lru" {
+		t.Errorf("unexpected record for SetCacheProvider: %v", recs[1])
+	}
+}
+
+func TestSetLogLevels(t *testing.T) {
+	w := testNewEngine(t)
+	defer w.Close()
+
+	var buf bytes.Buffer
+	w.SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	if err := w.SetEngineTarget(EngineTargetHighAccuracy); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("configuration changes should not be logged at info level by default")
+	}
+
+	w.SetLogLevels(LogLevels{Config: slog.LevelInfo})
+
+	if err := w.SetEngineTarget(EngineTargetHighPerformance); err != nil {
+		t.Fatal(err)
+	}
+
+	if recs := testLogRecords(t, &buf); len(recs) != 1 {
+		t.Errorf("expected 1 log record but got %d", len(recs))
+	}
+}
+
+func TestLoadLog(t *testing.T) {
+	w := testNewEngine(t)
+	defer w.Close()
+
+	var buf bytes.Buffer
+	w.SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	testLoadRepository(rootFile, w, t)
+
+	recs := testLogRecords(t, &buf)
+	if len(recs) != 1 {
+		t.Fatalf("expected 1 log record but got %d", len(recs))
+	}
+
+	if recs[0]["msg"] != "loaded repository" || recs[0]["root"] != rootFile || recs[0]["version"] == nil {
+		t.Errorf("unexpected record for Load: %v", recs[0])
+	}
+}
+
+func TestLoadLogFailed(t *testing.T) {
+	w := testNewEngine(t)
+	defer w.Close()
+
+	var buf bytes.Buffer
+	w.SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	if err := w.Load(); err == nil {
+		t.Fatalf("load should fail without a prior call to SetRoot()")
+	}
+
+	recs := testLogRecords(t, &buf)
+	if len(recs) != 1 || recs[0]["msg"] != "load failed" {
+		t.Errorf("expected a single load failed record but got %v", recs)
+	}
+}
@@ -0,0 +1,128 @@
+package gowurfl
+
+/*
+#include <stdlib.h>
+#if defined(__GLIBC__)
+#include <malloc.h>
+#include <stdio.h>
+
+// heap_info returns the report of malloc_info, which covers all arenas, or
+// NULL. The caller frees it.
+static char *heap_info(void) {
+	char *buf = NULL;
+	size_t n = 0;
+
+	FILE *f = open_memstream(&buf, &n);
+	if (f == NULL) {
+		return NULL;
+	}
+
+	int err = malloc_info(0, f);
+	fclose(f);
+
+	if (err != 0) {
+		free(buf);
+		return NULL;
+	}
+
+	return buf;
+}
+#else
+static char *heap_info(void) {
+	return NULL;
+}
+#endif
+*/
+import "C"
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// ErrorMemoryStatsUnsupported is returned by MemoryStats if the C library
+// cannot report its heap usage. Only glibc can.
+var ErrorMemoryStatsUnsupported = errors.New("C heap usage is not available with this C library")
+
+// MemoryStats approximates the memory held by an engine. The libwurfl cache
+// configured with SetCacheProvider is not included: it fills up after Load,
+// and libwurfl reports neither its entries nor its size.
+type MemoryStats struct {
+	// Repository is the growth of the C heap of the whole process during the
+	// last Load, i.e. the devices and capabilities loaded plus the C
+	// allocations of other goroutines, or other engines loading at the same
+	// time, made meanwhile. It is only exact if nothing else runs during Load.
+	Repository uint64
+	// Heap is the C heap in use by the whole process, not just the engine.
+	Heap uint64
+	// ResultCache is the estimated size of the attached ResultCache.
+	ResultCache int64
+}
+
+// heapInUse returns the bytes allocated on the C heap in all arenas, false if
+// unknown.
+func heapInUse() (uint64, bool) {
+	info := C.heap_info()
+	if info == nil {
+		return 0, false
+	}
+	defer C.free(unsafe.Pointer(info))
+
+	return parseMallocInfo(C.GoString(info))
+}
+
+var mallocInfoTotal = regexp.MustCompile(`<(?:total|system) type="(fast|rest|mmap|current)"(?: count="\d+")? size="(\d+)"/>`)
+
+// parseMallocInfo computes the bytes in use from the totals following the
+// last arena of a malloc_info report: the memory taken from the system in
+// arenas and mmapped chunks less the free chunks.
+func parseMallocInfo(s string) (uint64, bool) {
+	i := strings.LastIndex(s, "</heap>")
+	if i < 0 {
+		return 0, false
+	}
+
+	totals := make(map[string]uint64)
+	for _, m := range mallocInfoTotal.FindAllStringSubmatch(s[i:], -1) {
+		n, err := strconv.ParseUint(m[2], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		totals[m[1]] = n
+	}
+
+	if _, ok := totals["current"]; !ok {
+		return 0, false
+	}
+
+	used := totals["current"] + totals["mmap"]
+	free := totals["fast"] + totals["rest"]
+	if free > used {
+		return 0, true
+	}
+
+	return used - free, true
+}
+
+// MemoryStats reports the approximate memory held by the engine, see
+// MemoryStats. Comparing Repository across engines loaded with different
+// requested capabilities shows what restricting them saves.
+func (w *WURFL) MemoryStats() (MemoryStats, error) {
+	heap, ok := heapInUse()
+	if !ok {
+		return MemoryStats{}, ErrorMemoryStatsUnsupported
+	}
+
+	w.lifecycle.Lock()
+	ms := MemoryStats{Repository: w.repositoryHeap, Heap: heap}
+	w.lifecycle.Unlock()
+
+	if w.cache != nil {
+		ms.ResultCache = w.cache.Stats().Bytes
+	}
+
+	return ms, nil
+}
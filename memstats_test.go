@@ -0,0 +1,112 @@
+package gowurfl
+
+import (
+	"errors"
+	"testing"
+)
+
+// testCustomCapabilities is a capability set typical for an application
+// adapting its pages, used to compare memory usage.
+var testCustomCapabilities = []string{"brand_name", "model_name", "is_tablet", "resolution_width", "resolution_height"}
+
+func testMemoryStats(t testing.TB, caps []string) MemoryStats {
+	w := testNewEngine(t)
+	defer w.Close()
+
+	if err := w.AddRequestedCapabilities(caps); err != nil {
+		t.Fatal(err)
+	}
+
+	testLoadRepository(rootFile, w, t)
+
+	ms, err := w.MemoryStats()
+	if errors.Is(err, ErrorMemoryStatsUnsupported) {
+		t.Skip(err)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ms
+}
+
+const testMallocInfo = `<malloc version="1">
+<heap nr="0">
+<sizes>
+</sizes>
+<total type="fast" count="0" size="0"/>
+<total type="rest" count="1" size="130112"/>
+<system type="current" size="135168"/>
+<system type="max" size="135168"/>
+</heap>
+<heap nr="1">
+<sizes>
+  <unsorted from="657" to="657" total="657" count="1"/>
+</sizes>
+<total type="fast" count="2" size="64"/>
+<total type="rest" count="2" size="1457"/>
+<system type="current" size="1011712"/>
+<system type="max" size="1011712"/>
+</heap>
+<total type="fast" count="2" size="64"/>
+<total type="rest" count="3" size="131569"/>
+<total type="mmap" count="1" size="1052672"/>
+<system type="current" size="1146880"/>
+<system type="max" size="1146880"/>
+<aspace type="total" size="1146880"/>
+</malloc>
+`
+
+func TestParseMallocInfo(t *testing.T) {
+	// all arenas and the mmapped chunks, less the free chunks
+	want := uint64(1146880 + 1052672 - 64 - 131569)
+	if n, ok := parseMallocInfo(testMallocInfo); !ok || n != want {
+		t.Errorf("parseMallocInfo() returned %d, %v, want %d", n, ok, want)
+	}
+
+	if _, ok := parseMallocInfo("<malloc/>"); ok {
+		t.Errorf("parseMallocInfo() accepted a report without totals")
+	}
+}
+
+func TestMemoryStats(t *testing.T) {
+	full := testMemoryStats(t, nil)
+	mandatory := testMemoryStats(t, MandatoryCapabilities)
+
+	if full.Repository == 0 || full.Heap < full.Repository {
+		t.Errorf("implausible memory stats %+v", full)
+	}
+
+	if mandatory.Repository >= full.Repository {
+		t.Errorf("loading the mandatory capabilities took %d bytes, loading all %d", mandatory.Repository, full.Repository)
+	}
+}
+
+// BenchmarkLoadMemory reports the C heap taken by the repository as
+// repo-bytes, for all, the mandatory and a custom set of capabilities:
+//
+//	go test -run - -bench LoadMemory -benchtime 1x
+func BenchmarkLoadMemory(b *testing.B) {
+	if testing.Short() {
+		b.Skip("skipping load benchmark in short mode")
+	}
+
+	for _, bc := range []struct {
+		name string
+		caps []string
+	}{
+		{"Full", nil},
+		{"Mandatory", MandatoryCapabilities},
+		{"Custom", testCustomCapabilities},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			var total uint64
+			for i := 0; i < b.N; i++ {
+				total += testMemoryStats(b, bc.caps).Repository
+			}
+
+			b.ReportMetric(float64(total)/float64(b.N), "repo-bytes")
+		})
+	}
+}
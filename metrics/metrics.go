@@ -0,0 +1,321 @@
+// Package metrics instruments a gowurfl engine with Prometheus metrics.
+//
+// Wrap a *gowurfl.WURFL with New and use the returned Engine in its place;
+// lookups and loads done through the Engine are counted and timed, while all
+// other methods are passed through unchanged. A Snapshot carries no match type,
+// so lookups returning one are counted in lookups_total with the match type
+// "snapshot", whether they were served from the ResultCache or not. The
+// counters of the ResultCache are exported as result_cache_* series.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/knarz/gowurfl"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "wurfl"
+
+// snapshotMatchType is the match_type label of lookups returning a Snapshot.
+const snapshotMatchType = "snapshot"
+
+// sentinels maps the errors of gowurfl and of the context aware lookups to the
+// value of the error label.
+var sentinels = []struct {
+	err  error
+	name string
+}{
+	{gowurfl.ErrorInvalidHandle, "invalid_handle"},
+	{gowurfl.ErrorAlreadyLoad, "already_load"},
+	{gowurfl.ErrorFileNotFound, "file_not_found"},
+	{gowurfl.ErrorUnexpectedEndOfFile, "unexpected_end_of_file"},
+	{gowurfl.ErrorInputOutputFailure, "input_output_failure"},
+	{gowurfl.ErrorDeviceNotFound, "device_not_found"},
+	{gowurfl.ErrorCapabilityNotFound, "capability_not_found"},
+	{gowurfl.ErrorInvalidCapabilityValue, "invalid_capability_value"},
+	{gowurfl.ErrorVirtualCapabilityNotFound, "virtual_capability_not_found"},
+	{gowurfl.ErrorCantLoadCapabilityNotFound, "cant_load_capability_not_found"},
+	{gowurfl.ErrorCantLoadVirtualCapabilityNotFound, "cant_load_virtual_capability_not_found"},
+	{gowurfl.ErrorEmptyID, "empty_id"},
+	{gowurfl.ErrorCapabilityGroupNotFound, "capability_group_not_found"},
+	{gowurfl.ErrorCapabilityGroupMismatch, "capability_group_mismatch"},
+	{gowurfl.ErrorDeviceAlreadyDefined, "device_already_defined"},
+	{gowurfl.ErrorUseragentAlreadyDefined, "useragent_already_defined"},
+	{gowurfl.ErrorDeviceHierarchyCircularReference, "device_hierarchy_circular_reference"},
+	{gowurfl.ErrorInvalidUseragentPriority, "invalid_useragent_priority"},
+	{gowurfl.ErrorInvalidParameter, "invalid_parameter"},
+	{gowurfl.ErrorInvalidCacheSize, "invalid_cache_size"},
+	{gowurfl.ErrorXMLConsistency, "xml_consistency"},
+	{gowurfl.ErrorUnknown, "unknown"},
+	{gowurfl.ErrorLookupFailed, "lookup_failed"},
+	{gowurfl.ErrorClosed, "closed"},
+	{context.Canceled, "canceled"},
+	{context.DeadlineExceeded, "deadline_exceeded"},
+}
+
+// errorName returns the label value for err. Errors that do not wrap one of
+// the gowurfl sentinels are reported as "other".
+func errorName(err error) string {
+	for _, s := range sentinels {
+		if errors.Is(err, s.err) {
+			return s.name
+		}
+	}
+
+	return "other"
+}
+
+// Engine is an instrumented gowurfl engine.
+type Engine struct {
+	*gowurfl.WURFL
+
+	lookups        *prometheus.CounterVec
+	lookupDuration prometheus.Histogram
+	errors         *prometheus.CounterVec
+	loadDuration   prometheus.Gauge
+	loadTime       prometheus.Gauge
+}
+
+// New wraps w and registers its metrics with reg.
+func New(w *gowurfl.WURFL, reg prometheus.Registerer) (*Engine, error) {
+	e := &Engine{
+		WURFL: w,
+		lookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "lookups_total",
+			Help:      "Number of successful lookups by match type, \"snapshot\" for snapshot lookups.",
+		}, []string{"match_type"}),
+		lookupDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "lookup_duration_seconds",
+			Help:      "Latency of lookups.",
+			Buckets:   prometheus.ExponentialBuckets(0.000005, 4, 10),
+		}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "errors_total",
+			Help:      "Number of failed operations by operation and error.",
+		}, []string{"op", "error"}),
+		loadDuration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "load_duration_seconds",
+			Help:      "Duration of the last successful Load or LoadContext.",
+		}),
+		loadTime: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "load_timestamp_seconds",
+			Help:      "Unix time of the last successful Load or LoadContext.",
+		}),
+	}
+
+	cs := []prometheus.Collector{
+		e.lookups,
+		e.lookupDuration,
+		e.errors,
+		e.loadDuration,
+		e.loadTime,
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_hits_total",
+			Help:      "Number of lookups served from the libwurfl cache.",
+		}, func() float64 { return float64(w.CacheStats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_misses_total",
+			Help:      "Number of lookups not served from the libwurfl cache.",
+		}, func() float64 { return float64(w.CacheStats().Misses) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cache_hit_ratio",
+			Help:      "Share of lookups served from the libwurfl cache.",
+		}, func() float64 { return w.CacheStats().HitRate() }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "result_cache_hits_total",
+			Help:      "Number of snapshot lookups served from the ResultCache.",
+		}, func() float64 { return float64(e.resultCacheStats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "result_cache_misses_total",
+			Help:      "Number of snapshot lookups not served from the ResultCache.",
+		}, func() float64 { return float64(e.resultCacheStats().Misses) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "result_cache_evictions_total",
+			Help:      "Number of entries evicted from the ResultCache.",
+		}, func() float64 { return float64(e.resultCacheStats().Evictions) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "result_cache_entries",
+			Help:      "Number of snapshots held by the ResultCache.",
+		}, func() float64 { return float64(e.resultCacheStats().Entries) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "result_cache_bytes",
+			Help:      "Approximate memory held by the snapshots in the ResultCache.",
+		}, func() float64 { return float64(e.resultCacheStats().Bytes) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "data_file_age_seconds",
+			Help:      "Time since the release of the loaded root file.",
+		}, e.dataAge),
+	}
+
+	for _, c := range cs {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+func (e *Engine) dataAge() float64 {
+	age, err := e.DataAge()
+	if err != nil {
+		return 0
+	}
+
+	return age.Seconds()
+}
+
+// resultCacheStats returns the counters of the attached ResultCache, all zero
+// if there is none.
+func (e *Engine) resultCacheStats() gowurfl.ResultCacheStats {
+	if c := e.GetResultCache(); c != nil {
+		return c.Stats()
+	}
+
+	return gowurfl.ResultCacheStats{}
+}
+
+func (e *Engine) observeError(op string, err error) {
+	e.errors.WithLabelValues(op, errorName(err)).Inc()
+}
+
+// Load calls Load on the wrapped engine and records its duration.
+func (e *Engine) Load() error {
+	start := time.Now()
+
+	err := e.WURFL.Load()
+	e.observeLoad(start, err)
+
+	return err
+}
+
+// LoadContext calls LoadContext on the wrapped engine and records its
+// duration. An abandoned load is counted as an error.
+func (e *Engine) LoadContext(ctx context.Context, progress func(gowurfl.LoadProgress)) error {
+	start := time.Now()
+	err := e.WURFL.LoadContext(ctx, progress)
+	e.observeLoad(start, err)
+
+	return err
+}
+
+// observeLoad records the error of a load started at start or, if it
+// succeeded, its duration and time.
+func (e *Engine) observeLoad(start time.Time, err error) {
+	if err != nil {
+		e.observeError("load", err)
+		return
+	}
+
+	e.loadDuration.Set(time.Since(start).Seconds())
+	e.loadTime.Set(float64(time.Now().Unix()))
+}
+
+// observeLookup records the latency of a lookup started at start and its
+// error or the match type of the result, snapshotMatchType if d is nil.
+func (e *Engine) observeLookup(start time.Time, d *gowurfl.Device, err error) {
+	e.lookupDuration.Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		e.observeError("lookup", err)
+		return
+	}
+
+	mt := snapshotMatchType
+	if d != nil {
+		mt = d.GetMatchType().String()
+	}
+
+	e.lookups.WithLabelValues(mt).Inc()
+}
+
+// LookupUserAgent calls LookupUserAgent on the wrapped engine and records its
+// latency and the match type of the result.
+func (e *Engine) LookupUserAgent(ua string) (*gowurfl.Device, error) {
+	start := time.Now()
+	d, err := e.WURFL.LookupUserAgent(ua)
+	e.observeLookup(start, d, err)
+
+	return d, err
+}
+
+// LookupUserAgentContext is the instrumented LookupUserAgentContext.
+func (e *Engine) LookupUserAgentContext(ctx context.Context, ua string) (*gowurfl.Device, error) {
+	start := time.Now()
+	d, err := e.WURFL.LookupUserAgentContext(ctx, ua)
+	e.observeLookup(start, d, err)
+
+	return d, err
+}
+
+// LookupHeaders is the instrumented LookupHeaders.
+func (e *Engine) LookupHeaders(h http.Header) (*gowurfl.Device, error) {
+	start := time.Now()
+	d, err := e.WURFL.LookupHeaders(h)
+	e.observeLookup(start, d, err)
+
+	return d, err
+}
+
+// LookupHeadersContext is the instrumented LookupHeadersContext.
+func (e *Engine) LookupHeadersContext(ctx context.Context, h http.Header) (*gowurfl.Device, error) {
+	start := time.Now()
+	d, err := e.WURFL.LookupHeadersContext(ctx, h)
+	e.observeLookup(start, d, err)
+
+	return d, err
+}
+
+// LookupRequest is the instrumented LookupRequest.
+func (e *Engine) LookupRequest(r *http.Request) (*gowurfl.Device, error) {
+	start := time.Now()
+	d, err := e.WURFL.LookupRequest(r)
+	e.observeLookup(start, d, err)
+
+	return d, err
+}
+
+// LookupSnapshot is the instrumented LookupSnapshot.
+func (e *Engine) LookupSnapshot(ua string) (*gowurfl.Snapshot, error) {
+	start := time.Now()
+	s, err := e.WURFL.LookupSnapshot(ua)
+	e.observeLookup(start, nil, err)
+
+	return s, err
+}
+
+// LookupSnapshotContext is the instrumented LookupSnapshotContext.
+func (e *Engine) LookupSnapshotContext(ctx context.Context, ua string) (*gowurfl.Snapshot, error) {
+	start := time.Now()
+	s, err := e.WURFL.LookupSnapshotContext(ctx, ua)
+	e.observeLookup(start, nil, err)
+
+	return s, err
+}
+
+// LookupRequestSnapshot is the instrumented LookupRequestSnapshot.
+func (e *Engine) LookupRequestSnapshot(r *http.Request) (*gowurfl.Snapshot, error) {
+	start := time.Now()
+	s, err := e.WURFL.LookupRequestSnapshot(r)
+	e.observeLookup(start, nil, err)
+
+	return s, err
+}
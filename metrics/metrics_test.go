@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/knarz/gowurfl"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var rootFile = "/usr/share/wurfl/wurfl.xml"
+
+func testNewEngine(t testing.TB) *Engine {
+	w, err := gowurfl.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := New(w, prometheus.NewPedanticRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return e
+}
+
+func TestErrorName(t *testing.T) {
+	tcs := []struct {
+		in  error
+		out string
+	}{
+		{gowurfl.ErrorDeviceNotFound, "device_not_found"},
+		{fmt.Errorf("load: %w", gowurfl.ErrorFileNotFound), "file_not_found"},
+		{fmt.Errorf("user agent: %w", gowurfl.ErrorLookupFailed), "lookup_failed"},
+		{gowurfl.ErrorClosed, "closed"},
+		{fmt.Errorf("lookup: %w", context.DeadlineExceeded), "deadline_exceeded"},
+		{errors.New("failed to create important headers"), "other"},
+	}
+
+	for _, tc := range tcs {
+		if n := errorName(tc.in); n != tc.out {
+			t.Errorf("errorName(%q) expected %q but got %q", tc.in, tc.out, n)
+		}
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	w, err := gowurfl.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	reg := prometheus.NewRegistry()
+	if _, err := New(w, reg); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New(w, reg); err == nil {
+		t.Errorf("registering the same metrics twice should fail")
+	}
+}
+
+func TestLoadError(t *testing.T) {
+	e := testNewEngine(t)
+	defer e.Close()
+
+	if err := e.Load(); err == nil {
+		t.Fatalf("load should fail without a prior call to SetRoot()")
+	}
+
+	if n := testutil.CollectAndCount(e.errors); n != 1 {
+		t.Errorf("expected one error series but got %d", n)
+	}
+}
+
+func TestLookupUserAgent(t *testing.T) {
+	e := testNewEngine(t)
+	defer e.Close()
+
+	if err := e.SetRoot(rootFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := testutil.ToFloat64(e.loadTime); v == 0 {
+		t.Errorf("load time was not recorded")
+	}
+
+	ua := "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_11_4) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/52.0.2715.0 Safari/537.36"
+	for i := 0; i < 2; i++ {
+		d, err := e.LookupUserAgent(ua)
+		if err != nil {
+			t.Fatalf("LookupUserAgent(%q) failed with: %s", ua, err)
+		}
+		d.Close()
+	}
+
+	if v := testutil.ToFloat64(e.lookups.WithLabelValues(gowurfl.MatchTypeCached.String())); v != 1 {
+		t.Errorf("expected the second lookup to be cached but got %v cached lookups", v)
+	}
+}
+
+func TestLookupContextCanceled(t *testing.T) {
+	e := testNewEngine(t)
+	defer e.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := e.LookupUserAgentContext(ctx, "Mozilla/5.0"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("LookupUserAgentContext() with a canceled context returned %v", err)
+	}
+
+	if v := testutil.ToFloat64(e.errors.WithLabelValues("lookup", "canceled")); v != 1 {
+		t.Errorf("expected one canceled lookup but got %v", v)
+	}
+}
+
+func TestLookupSnapshotResultCache(t *testing.T) {
+	w, err := gowurfl.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	reg := prometheus.NewPedanticRegistry()
+	e, err := New(w, reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := gowurfl.NewResultCache(gowurfl.ResultCacheConfig{})
+	e.SetResultCache(c)
+	c.Add("Dillo/2.0", gowurfl.NewSnapshot("dillo_ver2", nil, nil))
+
+	if _, err := e.LookupSnapshotContext(context.Background(), "Dillo/2.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := testutil.ToFloat64(e.lookups.WithLabelValues("snapshot")); v != 1 {
+		t.Errorf("expected one snapshot lookup but got %v", v)
+	}
+
+	want := `
+# HELP wurfl_result_cache_entries Number of snapshots held by the ResultCache.
+# TYPE wurfl_result_cache_entries gauge
+wurfl_result_cache_entries 1
+# HELP wurfl_result_cache_hits_total Number of snapshot lookups served from the ResultCache.
+# TYPE wurfl_result_cache_hits_total counter
+wurfl_result_cache_hits_total 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "wurfl_result_cache_entries", "wurfl_result_cache_hits_total"); err != nil {
+		t.Error(err)
+	}
+}
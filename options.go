@@ -0,0 +1,207 @@
+package gowurfl
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+type options struct {
+	target      *EngineTarget
+	priority    *UserAgentPriority
+	cache       *CacheProvider
+	cacheSizes  []int
+	caps        []string
+	patches     []string
+	logger      *slog.Logger
+	resultCache *ResultCache
+	recorder    *CapabilityRecorder
+	profiles    []string
+	validate    bool
+}
+
+// Option configures an engine created by Open.
+type Option func(*options)
+
+// WithEngineTarget sets the engine target, see SetEngineTarget.
+func WithEngineTarget(et EngineTarget) Option {
+	return func(o *options) {
+		o.target = &et
+	}
+}
+
+// WithUserAgentPriority sets the user agent priority, see
+// SetUserAgentPriority.
+func WithUserAgentPriority(p UserAgentPriority) Option {
+	return func(o *options) {
+		o.priority = &p
+	}
+}
+
+// WithCache sets the libwurfl cache provider, see SetCacheProvider.
+func WithCache(c CacheProvider, sizes ...int) Option {
+	return func(o *options) {
+		o.cache = &c
+		o.cacheSizes = sizes
+	}
+}
+
+// WithCapabilities restricts loading to the given capabilities, see
+// AddRequestedCapabilities. It may be given multiple times.
+func WithCapabilities(caps ...string) Option {
+	return func(o *options) {
+		o.caps = append(o.caps, caps...)
+	}
+}
+
+// WithPatches adds patch files, see AddPatch. It may be given multiple times.
+func WithPatches(patches ...string) Option {
+	return func(o *options) {
+		o.patches = append(o.patches, patches...)
+	}
+}
+
+// WithLogger attaches a logger, see SetLogger. It is attached first, so the
+// remaining configuration is logged as well.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithResultCache attaches a ResultCache, see SetResultCache.
+func WithResultCache(c *ResultCache) Option {
+	return func(o *options) {
+		o.resultCache = c
+	}
+}
+
+// WithCapabilityRecorder attaches a CapabilityRecorder, see
+// SetCapabilityRecorder.
+func WithCapabilityRecorder(r *CapabilityRecorder) Option {
+	return func(o *options) {
+		o.recorder = r
+	}
+}
+
+// WithCapabilityProfile restricts loading to the capabilities and virtual
+// capabilities listed in the profile file p, see CapabilityProfile. It may be
+// given multiple times and combined with WithCapabilities. An empty profile
+// loads everything.
+func WithCapabilityProfile(p string) Option {
+	return func(o *options) {
+		o.profiles = append(o.profiles, p)
+	}
+}
+
+// WithCapabilityValidation runs ValidateCapabilities after loading and makes
+// Open fail with all findings joined if there are any.
+func WithCapabilityValidation() Option {
+	return func(o *options) {
+		o.validate = true
+	}
+}
+
+// Open creates an engine, configures it with opts, sets root as the root file
+// and loads it. The options are applied in the order libwurfl requires
+// regardless of the order they are given in. If any step fails, the handle is
+// released and the error returned.
+func Open(root string, opts ...Option) (*WURFL, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	w, err := New()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := o.apply(w, root); err != nil {
+		w.Close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (o *options) apply(w *WURFL, root string) error {
+	if o.logger != nil {
+		w.SetLogger(o.logger)
+	}
+
+	if o.target != nil {
+		if err := w.SetEngineTarget(*o.target); err != nil {
+			return err
+		}
+	}
+
+	if o.priority != nil {
+		if err := w.SetUserAgentPriority(*o.priority); err != nil {
+			return err
+		}
+	}
+
+	if o.cache != nil {
+		if err := w.SetCacheProvider(*o.cache, o.cacheSizes...); err != nil {
+			return err
+		}
+	}
+
+	if err := w.AddRequestedCapabilities(o.caps); err != nil {
+		return err
+	}
+
+	for _, p := range o.profiles {
+		prof, err := readCapabilityProfileFile(p)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+
+		if err := w.AddRequestedCapabilities(prof.Capabilities); err != nil {
+			return err
+		}
+
+		if err := w.AddRequestedCapabilities(prof.VirtualCapabilities); err != nil {
+			return err
+		}
+	}
+
+	if err := w.SetRoot(root); err != nil {
+		return err
+	}
+
+	for _, p := range o.patches {
+		if err := w.AddPatch(p); err != nil {
+			return err
+		}
+	}
+
+	if o.resultCache != nil {
+		w.SetResultCache(o.resultCache)
+	}
+
+	if o.recorder != nil {
+		w.SetCapabilityRecorder(o.recorder)
+	}
+
+	if err := w.Load(); err != nil {
+		return err
+	}
+
+	if !o.validate {
+		return nil
+	}
+
+	found, err := w.ValidateCapabilities()
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, len(found))
+	for i, f := range found {
+		errs[i] = f
+	}
+
+	return errors.Join(errs...)
+}
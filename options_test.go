@@ -0,0 +1,50 @@
+package gowurfl
+
+import (
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	w, err := Open(rootFile,
+		WithCapabilities(MandatoryCapabilities...),
+		WithCache(CacheProviderLRU, 1000),
+		WithEngineTarget(EngineTargetHighAccuracy),
+	)
+	if err != nil {
+		t.Fatalf("Open() failed with: %s", err)
+	}
+	defer w.Close()
+
+	if et := w.GetEngineTarget(); et != EngineTargetHighAccuracy {
+		t.Errorf("expected engine target %v but got %v", EngineTargetHighAccuracy, et)
+	}
+
+	if p, _ := w.GetCacheProvider(); p != CacheProviderLRU {
+		t.Errorf("expected cache provider %v but got %v", CacheProviderLRU, p)
+	}
+
+	for _, cap := range MandatoryCapabilities {
+		if !w.HasCapability(cap) {
+			t.Errorf("should have capability %q", cap)
+		}
+	}
+}
+
+func TestOpenFails(t *testing.T) {
+	tcs := []struct {
+		root string
+		opts []Option
+	}{
+		{"/nonexistent/wurfl.xml", nil},
+		{rootFile, []Option{WithEngineTarget(EngineTargetInvalid)}},
+		{rootFile, []Option{WithCache(CacheProviderLRU, 0)}},
+		{rootFile, []Option{WithPatches("/nonexistent/patch.xml")}},
+	}
+
+	for _, tc := range tcs {
+		if w, err := Open(tc.root, tc.opts...); err == nil {
+			w.Close()
+			t.Errorf("Open(%q) with %d options expected to fail but did not", tc.root, len(tc.opts))
+		}
+	}
+}
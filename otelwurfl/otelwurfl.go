@@ -0,0 +1,254 @@
+// Package otelwurfl adds OpenTelemetry tracing to a gowurfl engine.
+//
+// Wrap a *gowurfl.WURFL with New and use the context aware methods,
+// LookupRequest and LookupRequestSnapshot of the returned Engine. Unless a
+// tracer provider is passed with WithTracerProvider or registered globally
+// with otel.SetTracerProvider, the spans are no-ops.
+// User agents are only recorded with WithUserAgentAttribute, since they may
+// identify users and can be long.
+package otelwurfl
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/knarz/gowurfl"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/knarz/gowurfl/otelwurfl"
+
+var (
+	attrRoot       = attribute.Key("wurfl.root")
+	attrUserAgent  = attribute.Key("wurfl.user_agent")
+	attrDeviceID   = attribute.Key("wurfl.device_id")
+	attrMatchType  = attribute.Key("wurfl.match_type")
+	attrCacheHit   = attribute.Key("wurfl.cache_hit")
+	attrCapability = attribute.Key("wurfl.capability")
+)
+
+type config struct {
+	tp           trace.TracerProvider
+	userAgent    bool
+	userAgentMax int
+}
+
+// Option configures an Engine.
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider used to create spans. The global
+// provider is used if this option is not given.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tp = tp
+	}
+}
+
+// WithUserAgentAttribute records the looked up user agent in the
+// wurfl.user_agent attribute of lookup spans, truncated to max bytes unless max
+// is zero or negative.
+func WithUserAgentAttribute(max int) Option {
+	return func(c *config) {
+		c.userAgent = true
+		c.userAgentMax = max
+	}
+}
+
+// Engine is a traced gowurfl engine.
+type Engine struct {
+	*gowurfl.WURFL
+
+	tracer       trace.Tracer
+	userAgent    bool
+	userAgentMax int
+}
+
+// New wraps w.
+func New(w *gowurfl.WURFL, opts ...Option) *Engine {
+	c := config{tp: otel.GetTracerProvider()}
+	for _, o := range opts {
+		o(&c)
+	}
+
+	return &Engine{
+		WURFL:        w,
+		tracer:       c.tp.Tracer(instrumentationName),
+		userAgent:    c.userAgent,
+		userAgentMax: c.userAgentMax,
+	}
+}
+
+// userAgentAttributes returns the attributes describing ua, none unless
+// enabled with WithUserAgentAttribute.
+func (e *Engine) userAgentAttributes(ua string) []attribute.KeyValue {
+	if !e.userAgent {
+		return nil
+	}
+
+	if e.userAgentMax > 0 && len(ua) > e.userAgentMax {
+		ua = strings.ToValidUTF8(ua[:e.userAgentMax], "")
+	}
+
+	return []attribute.KeyValue{attrUserAgent.String(ua)}
+}
+
+func finish(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// LoadContext calls LoadContext on the wrapped engine within a span.
+func (e *Engine) LoadContext(ctx context.Context, progress func(gowurfl.LoadProgress)) (err error) {
+	ctx, span := e.tracer.Start(ctx, "wurfl.Load", trace.WithAttributes(attrRoot.String(e.GetRoot())))
+	defer func() { finish(span, err) }()
+
+	return e.WURFL.LoadContext(ctx, progress)
+}
+
+// LookupUserAgentContext calls LookupUserAgentContext on the wrapped engine
+// within a span carrying the id and match type of the device.
+func (e *Engine) LookupUserAgentContext(ctx context.Context, ua string) (d *gowurfl.Device, err error) {
+	ctx, span := e.tracer.Start(ctx, "wurfl.LookupUserAgent", trace.WithAttributes(e.userAgentAttributes(ua)...))
+	defer func() { finish(span, err) }()
+
+	d, err = e.WURFL.LookupUserAgentContext(ctx, ua)
+	if err != nil {
+		return nil, err
+	}
+
+	annotate(span, d)
+
+	return d, nil
+}
+
+// LookupHeadersContext calls LookupHeadersContext on the wrapped engine within
+// a span carrying the id and match type of the device.
+func (e *Engine) LookupHeadersContext(ctx context.Context, h http.Header) (d *gowurfl.Device, err error) {
+	ctx, span := e.tracer.Start(ctx, "wurfl.LookupHeaders", trace.WithAttributes(e.userAgentAttributes(h.Get("User-Agent"))...))
+	defer func() { finish(span, err) }()
+
+	d, err = e.WURFL.LookupHeadersContext(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+
+	annotate(span, d)
+
+	return d, nil
+}
+
+// LookupRequest calls LookupHeadersContext on the wrapped engine with the
+// headers of r within a span started from the context of r.
+func (e *Engine) LookupRequest(r *http.Request) (d *gowurfl.Device, err error) {
+	ctx, span := e.tracer.Start(r.Context(), "wurfl.LookupRequest", trace.WithAttributes(e.userAgentAttributes(r.UserAgent())...))
+	defer func() { finish(span, err) }()
+
+	d, err = e.WURFL.LookupHeadersContext(ctx, r.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	annotate(span, d)
+
+	return d, nil
+}
+
+// LookupSnapshotContext calls LookupSnapshotContext on the wrapped engine
+// within a span carrying the id of the device and whether it was served from
+// the ResultCache.
+func (e *Engine) LookupSnapshotContext(ctx context.Context, ua string) (s *gowurfl.Snapshot, err error) {
+	ctx, span := e.tracer.Start(ctx, "wurfl.LookupSnapshot", trace.WithAttributes(e.userAgentAttributes(ua)...))
+	defer func() { finish(span, err) }()
+
+	var lt gowurfl.LookupTrace
+	s, err = e.WURFL.LookupSnapshotContext(gowurfl.WithLookupTrace(ctx, &lt), ua)
+	if err != nil {
+		return nil, err
+	}
+
+	annotateSnapshot(span, s, lt.CacheHit)
+
+	return s, nil
+}
+
+// LookupRequestSnapshot calls LookupRequestSnapshot on the wrapped engine
+// within a span started from the context of r, carrying the id of the device
+// and whether it was served from the ResultCache.
+func (e *Engine) LookupRequestSnapshot(r *http.Request) (s *gowurfl.Snapshot, err error) {
+	ctx, span := e.tracer.Start(r.Context(), "wurfl.LookupRequestSnapshot", trace.WithAttributes(e.userAgentAttributes(r.UserAgent())...))
+	defer func() { finish(span, err) }()
+
+	var lt gowurfl.LookupTrace
+	s, err = e.WURFL.LookupRequestSnapshot(r.WithContext(gowurfl.WithLookupTrace(ctx, &lt)))
+	if err != nil {
+		return nil, err
+	}
+
+	annotateSnapshot(span, s, lt.CacheHit)
+
+	return s, nil
+}
+
+func annotate(span trace.Span, d *gowurfl.Device) {
+	if !span.IsRecording() {
+		return
+	}
+
+	mt := d.GetMatchType()
+	span.SetAttributes(
+		attrMatchType.String(mt.String()),
+		attrCacheHit.Bool(mt == gowurfl.MatchTypeCached),
+	)
+
+	annotateID(span, d)
+}
+
+func annotateSnapshot(span trace.Span, s *gowurfl.Snapshot, cacheHit bool) {
+	if !span.IsRecording() {
+		return
+	}
+
+	span.SetAttributes(attrCacheHit.Bool(cacheHit))
+
+	annotateID(span, s)
+}
+
+// annotateID sets the device id of d, a *gowurfl.Device or *gowurfl.Snapshot.
+func annotateID(span trace.Span, d interface{ GetID() (string, error) }) {
+	if !span.IsRecording() {
+		return
+	}
+
+	if id, err := d.GetID(); err == nil {
+		span.SetAttributes(attrDeviceID.String(id))
+	}
+}
+
+// GetCapabilityContext fetches the capability of d within a span.
+func (e *Engine) GetCapabilityContext(ctx context.Context, d *gowurfl.Device, name string) (v string, err error) {
+	_, span := e.tracer.Start(ctx, "wurfl.GetCapability", trace.WithAttributes(attrCapability.String(name)))
+	defer func() { finish(span, err) }()
+
+	annotateID(span, d)
+
+	return d.GetCapability(name)
+}
+
+// GetVirtualCapabilityContext fetches the virtual capability of d within a
+// span.
+func (e *Engine) GetVirtualCapabilityContext(ctx context.Context, d *gowurfl.Device, name string) (v string, err error) {
+	_, span := e.tracer.Start(ctx, "wurfl.GetVirtualCapability", trace.WithAttributes(attrCapability.String(name)))
+	defer func() { finish(span, err) }()
+
+	annotateID(span, d)
+
+	return d.GetVirtualCapability(name)
+}
@@ -0,0 +1,201 @@
+package otelwurfl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/knarz/gowurfl"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var rootFile = "/usr/share/wurfl/wurfl.xml"
+
+func testNewEngine(t testing.TB) (*Engine, *tracetest.InMemoryExporter) {
+	w, err := gowurfl.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+
+	return New(w, WithTracerProvider(tp)), exp
+}
+
+func TestLoadContextError(t *testing.T) {
+	e, exp := testNewEngine(t)
+	defer e.Close()
+
+	if err := e.LoadContext(context.Background(), nil); err == nil {
+		t.Fatalf("load should fail without a prior call to SetRoot()")
+	}
+
+	spans := exp.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected one span but got %d", len(spans))
+	}
+
+	if spans[0].Name != "wurfl.Load" || spans[0].Status.Code != codes.Error {
+		t.Errorf("unexpected span: %s %v", spans[0].Name, spans[0].Status)
+	}
+}
+
+func TestLookupUserAgentContext(t *testing.T) {
+	e, exp := testNewEngine(t)
+	defer e.Close()
+
+	if err := e.SetRoot(rootFile); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := e.LoadContext(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ua := "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_11_4) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/52.0.2715.0 Safari/537.36"
+	d, err := e.LookupUserAgentContext(ctx, ua)
+	if err != nil {
+		t.Fatalf("LookupUserAgentContext(%q) failed with: %s", ua, err)
+	}
+	defer d.Close()
+
+	if _, err := e.GetVirtualCapabilityContext(ctx, d, "is_mobile"); err != nil {
+		t.Errorf("GetVirtualCapabilityContext() failed with: %s", err)
+	}
+
+	spans := exp.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected three spans but got %d", len(spans))
+	}
+
+	attrs := make(map[string]bool)
+	for _, kv := range spans[1].Attributes {
+		attrs[string(kv.Key)] = true
+	}
+
+	for _, k := range []string{"wurfl.device_id", "wurfl.match_type", "wurfl.cache_hit"} {
+		if !attrs[k] {
+			t.Errorf("lookup span is missing attribute %q", k)
+		}
+	}
+}
+
+func TestLookupRequest(t *testing.T) {
+	e, exp := testNewEngine(t)
+	defer e.Close()
+
+	if err := e.SetRoot(rootFile); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := e.LoadContext(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("User-Agent", "Opera/9.80 (J2ME/MIDP; Opera Mini/9.80 (S60; SymbOS; Opera Mobi/23.348; U; en) Presto/2.5.25 Version/10.54")
+	r.Header.Set("X-OperaMini-Phone-UA", "Nokia6300/2.0 (05.00) Profile/MIDP-2.0 Configuration/CLDC-1.1")
+
+	d, err := e.LookupRequest(r)
+	if err != nil {
+		t.Fatalf("LookupRequest() failed with: %s", err)
+	}
+	d.Close()
+
+	d, err = e.LookupHeadersContext(ctx, r.Header)
+	if err != nil {
+		t.Fatalf("LookupHeadersContext() failed with: %s", err)
+	}
+	d.Close()
+
+	spans := exp.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected three spans but got %d", len(spans))
+	}
+
+	for i, name := range []string{"wurfl.LookupRequest", "wurfl.LookupHeaders"} {
+		if spans[1+i].Name != name {
+			t.Errorf("expected span %q but got %q", name, spans[1+i].Name)
+		}
+
+		for _, kv := range spans[1+i].Attributes {
+			if kv.Key == attrUserAgent {
+				t.Errorf("span %q records the user agent without WithUserAgentAttribute", name)
+			}
+		}
+	}
+}
+
+func TestLookupSnapshotContextCacheHit(t *testing.T) {
+	e, exp := testNewEngine(t)
+	defer e.Close()
+
+	c := gowurfl.NewResultCache(gowurfl.ResultCacheConfig{})
+	e.SetResultCache(c)
+	c.Add("Dillo/2.0", gowurfl.NewSnapshot("dillo_ver2", nil, nil))
+
+	if _, err := e.LookupSnapshotContext(context.Background(), "Dillo/2.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exp.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected one span but got %d", len(spans))
+	}
+
+	want := []attribute.KeyValue{attrCacheHit.Bool(true), attrDeviceID.String("dillo_ver2")}
+	if !reflect.DeepEqual(spans[0].Attributes, want) {
+		t.Errorf("span %q has attributes %v, want %v", spans[0].Name, spans[0].Attributes, want)
+	}
+}
+
+func TestNoopTracerProvider(t *testing.T) {
+	w, err := gowurfl.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	e := New(w, WithTracerProvider(noop.NewTracerProvider()))
+
+	_, span := e.tracer.Start(context.Background(), "test")
+	if span.IsRecording() {
+		t.Errorf("spans of the noop provider should not record")
+	}
+}
+
+func TestUserAgentAttributes(t *testing.T) {
+	w, err := gowurfl.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	ua := "Mozilla/5.0 (Linux; Android 4.4.2; Lenovo A7600-F) Käse"
+
+	tcs := []struct {
+		opts []Option
+		want []attribute.KeyValue
+	}{
+		{nil, nil},
+		{[]Option{WithUserAgentAttribute(0)}, []attribute.KeyValue{attrUserAgent.String(ua)}},
+		{[]Option{WithUserAgentAttribute(11)}, []attribute.KeyValue{attrUserAgent.String("Mozilla/5.0")}},
+		// the cut falls into the ä, which is dropped
+		{[]Option{WithUserAgentAttribute(len(ua) - 3)}, []attribute.KeyValue{attrUserAgent.String(ua[:len(ua)-4])}},
+	}
+
+	for i, tc := range tcs {
+		if have := New(w, tc.opts...).userAgentAttributes(ua); !reflect.DeepEqual(have, tc.want) {
+			t.Errorf("%d: userAgentAttributes()\nwant: %v\nhave: %v", i, tc.want, have)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package gowurfl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Golden is the expected detection result for a user agent. Only the
+// capabilities listed are compared, so a golden file can pin down as much or
+// as little as needed.
+type Golden struct {
+	UserAgent           string            `json:"user_agent"`
+	ID                  string            `json:"id"`
+	Capabilities        map[string]string `json:"capabilities,omitempty"`
+	VirtualCapabilities map[string]string `json:"virtual_capabilities,omitempty"`
+}
+
+// GoldenDiff is a difference between a golden entry and the current
+// detection. Field is "id", "error", the name of a capability or the name of a
+// virtual capability prefixed with "virtual:".
+type GoldenDiff struct {
+	UserAgent string `json:"user_agent"`
+	Field     string `json:"field"`
+	Want      string `json:"want"`
+	Have      string `json:"have"`
+}
+
+func (d GoldenDiff) String() string {
+	return fmt.Sprintf("%q: %s: want %q, have %q", d.UserAgent, d.Field, d.Want, d.Have)
+}
+
+// RecordGolden looks up the user agents and records their device ids and the
+// values of the given capabilities and virtual capabilities. User agents that
+// fail are left out and reported joined in the error, the entries of all
+// others are returned nonetheless.
+func (w *WURFL) RecordGolden(uas, caps, vcaps []string) ([]Golden, error) {
+	var errs []error
+	golden := w.recordGolden(uas, caps, vcaps, func(ua string, err error) {
+		errs = append(errs, fmt.Errorf("%q: %w", ua, err))
+	})
+
+	return golden, errors.Join(errs...)
+}
+
+// recordGolden is RecordGolden calling failed for every user agent that
+// fails.
+func (w *WURFL) recordGolden(uas, caps, vcaps []string, failed func(ua string, err error)) []Golden {
+	golden := make([]Golden, 0, len(uas))
+
+	for _, ua := range uas {
+		g, err := w.recordGoldenEntry(ua, caps, vcaps)
+		if err != nil {
+			failed(ua, err)
+			continue
+		}
+
+		golden = append(golden, g)
+	}
+
+	return golden
+}
+
+func (w *WURFL) recordGoldenEntry(ua string, caps, vcaps []string) (Golden, error) {
+	s, err := w.LookupSnapshot(ua)
+	if err != nil {
+		return Golden{}, err
+	}
+
+	g := Golden{UserAgent: ua, ID: s.id}
+
+	if len(caps) > 0 {
+		g.Capabilities = make(map[string]string, len(caps))
+	}
+	for _, c := range caps {
+		if g.Capabilities[c], err = s.GetCapability(c); err != nil {
+			return Golden{}, fmt.Errorf("%s: %w", c, err)
+		}
+	}
+
+	if len(vcaps) > 0 {
+		g.VirtualCapabilities = make(map[string]string, len(vcaps))
+	}
+	for _, c := range vcaps {
+		if g.VirtualCapabilities[c], err = s.GetVirtualCapability(c); err != nil {
+			return Golden{}, fmt.Errorf("%s: %w", c, err)
+		}
+	}
+
+	return g, nil
+}
+
+// CheckGolden looks up the user agent of every entry and returns how the
+// detection differs from the expected one. A failed lookup is reported as a
+// difference in the field "error".
+func (w *WURFL) CheckGolden(golden []Golden) []GoldenDiff {
+	var diffs []GoldenDiff
+
+	for _, g := range golden {
+		s, err := w.LookupSnapshot(g.UserAgent)
+		if err != nil {
+			diffs = append(diffs, GoldenDiff{UserAgent: g.UserAgent, Field: "error", Have: err.Error()})
+			continue
+		}
+
+		diffs = append(diffs, g.diff(s)...)
+	}
+
+	return diffs
+}
+
+// diff compares the entry with a snapshot. Capabilities missing from the
+// snapshot are reported with the value "<missing>".
+func (g Golden) diff(s *Snapshot) []GoldenDiff {
+	var diffs []GoldenDiff
+
+	add := func(field, want, have string) {
+		if want != have {
+			diffs = append(diffs, GoldenDiff{g.UserAgent, field, want, have})
+		}
+	}
+
+	add("id", g.ID, s.id)
+
+	for _, c := range sortedKeys(g.Capabilities) {
+		v, err := s.GetCapability(c)
+		if err != nil {
+			v = "<missing>"
+		}
+		add(c, g.Capabilities[c], v)
+	}
+
+	for _, c := range sortedKeys(g.VirtualCapabilities) {
+		v, err := s.GetVirtualCapability(c)
+		if err != nil {
+			v = "<missing>"
+		}
+		add("virtual:"+c, g.VirtualCapabilities[c], v)
+	}
+
+	return diffs
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// ReadGolden reads a golden file as written by WriteGolden.
+func ReadGolden(r io.Reader) ([]Golden, error) {
+	var golden []Golden
+	if err := json.NewDecoder(r).Decode(&golden); err != nil {
+		return nil, err
+	}
+
+	return golden, nil
+}
+
+// WriteGolden writes the entries as an indented JSON array, which keeps the
+// diffs of updated golden files readable.
+func WriteGolden(w io.Writer, golden []Golden) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+
+	return enc.Encode(golden)
+}
@@ -0,0 +1,109 @@
+package gowurfl
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"reflect"
+	"testing"
+)
+
+var (
+	update     = flag.Bool("update", false, "record testdata/golden.json instead of comparing against it")
+	goldenRoot = flag.String("golden.root", rootFile, "root file TestGolden looks up against")
+)
+
+const goldenFile = "testdata/golden.json"
+
+// goldenVirtualCapabilities are recorded for every user agent in uas next to
+// the device id.
+var goldenVirtualCapabilities = []string{"form_factor", "is_mobile", "is_robot", "advertised_device_os", "advertised_browser"}
+
+// TestGolden compares the detection of uas against testdata/golden.json, which
+// depends on the root file and is not part of the repository. Run it with
+// -update to record the file and after reviewing a data update to accept the
+// new results.
+func TestGolden(t *testing.T) {
+	w := testNewEngine(t)
+	defer w.Close()
+
+	testLoadRepository(*goldenRoot, w, t)
+
+	if *update {
+		golden, err := w.RecordGolden(uas, MandatoryCapabilities, goldenVirtualCapabilities)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := os.Create(goldenFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		if err := WriteGolden(f, golden); err != nil {
+			t.Fatal(err)
+		}
+
+		return
+	}
+
+	f, err := os.Open(goldenFile)
+	if os.IsNotExist(err) {
+		t.Skipf("%s does not exist, record it from the test root with: go test -run TestGolden -update", goldenFile)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	golden, err := ReadGolden(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range w.CheckGolden(golden) {
+		t.Error(d)
+	}
+}
+
+func TestGoldenDiff(t *testing.T) {
+	g := Golden{
+		UserAgent:           "Dillo/2.0",
+		ID:                  "dillo_ver2",
+		Capabilities:        map[string]string{"brand_name": "Dillo", "is_wireless_device": "false"},
+		VirtualCapabilities: map[string]string{"form_factor": "Desktop", "is_robot": "false"},
+	}
+	s := &Snapshot{
+		id:                  "generic_web_browser",
+		capabilities:        Capabilities{"brand_name": "Dillo", "is_wireless_device": "false"},
+		virtualCapabilities: Capabilities{"form_factor": "Desktop"},
+	}
+
+	want := []GoldenDiff{
+		{"Dillo/2.0", "id", "dillo_ver2", "generic_web_browser"},
+		{"Dillo/2.0", "virtual:is_robot", "false", "<missing>"},
+	}
+
+	if d := g.diff(s); !reflect.DeepEqual(d, want) {
+		t.Errorf("diff()\nwant: %v\nhave: %v", want, d)
+	}
+
+	var b bytes.Buffer
+	if err := WriteGolden(&b, []Golden{g}); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadGolden(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(read, []Golden{g}) {
+		t.Errorf("ReadGolden() does not return what WriteGolden() wrote\nwant: %v\nhave: %v", []Golden{g}, read)
+	}
+}
@@ -0,0 +1,171 @@
+package gowurfl
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReloadGrace is the minimum time a replaced engine is kept open after a
+// reload, so work that fetched it with Current can finish. Engines fetched
+// with Acquire are kept open until they are released as well.
+var ReloadGrace = time.Minute
+
+// Reloader keeps a loaded engine around and periodically replaces it with a
+// freshly opened one, e.g. to pick up an updated root file.
+// Callers fetch the engine with Acquire for every unit of work and release it
+// once they are done with it and the devices looked up from it. Callers using
+// Current instead must not hold on to the engine for longer than ReloadGrace.
+type Reloader struct {
+	open     func() (*WURFL, error)
+	interval time.Duration
+
+	mu      sync.Mutex
+	current atomic.Pointer[reloadedEngine]
+	retired map[*reloadedEngine]*time.Timer
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// reloadedEngine counts the users of an engine, which is closed once it is
+// retired and the last user released it.
+type reloadedEngine struct {
+	w       *WURFL
+	refs    atomic.Int64
+	retired atomic.Bool
+	close   sync.Once
+}
+
+func (e *reloadedEngine) release() {
+	if e.refs.Add(-1) == 0 && e.retired.Load() {
+		e.close.Do(e.w.Close)
+	}
+}
+
+func (e *reloadedEngine) retire() {
+	e.retired.Store(true)
+	if e.refs.Load() == 0 {
+		e.close.Do(e.w.Close)
+	}
+}
+
+// NewReloader opens the first engine with open and, if interval is positive,
+// replaces it every interval. Failed reloads are logged through the logger of
+// the current engine, which is kept in that case.
+func NewReloader(open func() (*WURFL, error), interval time.Duration) (*Reloader, error) {
+	w, err := open()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reloader{
+		open:     open,
+		interval: interval,
+		retired:  make(map[*reloadedEngine]*time.Timer),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	r.current.Store(&reloadedEngine{w: w})
+
+	if interval > 0 {
+		go r.loop()
+	} else {
+		close(r.done)
+	}
+
+	return r, nil
+}
+
+func (r *Reloader) loop() {
+	defer close(r.done)
+
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			r.Reload()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Current returns the engine currently in use. It is closed ReloadGrace after
+// it was replaced, see Acquire for a safe alternative.
+func (r *Reloader) Current() *WURFL {
+	return r.current.Load().w
+}
+
+// Acquire returns the engine currently in use and a function releasing it,
+// which must be called exactly once, after the devices looked up from the
+// engine were closed. A replaced engine is closed only once all its users
+// released it.
+func (r *Reloader) Acquire() (*WURFL, func()) {
+	for {
+		e := r.current.Load()
+		e.refs.Add(1)
+
+		// the engine might have been replaced in the meantime
+		if r.current.Load() == e {
+			var once sync.Once
+			return e.w, func() { once.Do(e.release) }
+		}
+
+		e.release()
+	}
+}
+
+// Reload opens a new engine and swaps it in. The replaced engine is closed
+// after ReloadGrace, once all users that acquired it released it.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old := r.current.Load()
+	start := time.Now()
+
+	w, err := r.open()
+	if err != nil {
+		old.w.log(old.w.levels.Errors, "reload failed", "root", old.w.path, "error", err)
+		return err
+	}
+
+	r.current.Store(&reloadedEngine{w: w})
+	w.log(w.levels.Reload, "reloaded repository", "root", w.path, "duration", time.Since(start))
+
+	r.retired[old] = time.AfterFunc(ReloadGrace, func() {
+		r.mu.Lock()
+		delete(r.retired, old)
+		r.mu.Unlock()
+
+		old.retire()
+	})
+
+	return nil
+}
+
+// Close stops reloading and closes the current engine as well as replaced
+// engines still waiting for ReloadGrace to pass. Engines still acquired are
+// closed when they are released.
+func (r *Reloader) Close() {
+	select {
+	case <-r.stop:
+	default:
+		close(r.stop)
+	}
+	<-r.done
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for e, t := range r.retired {
+		t.Stop()
+		delete(r.retired, e)
+		e.retire()
+	}
+
+	r.current.Load().retire()
+}
@@ -0,0 +1,223 @@
+// Package report aggregates the devices behind a stream of user agents into
+// tables of counts, e.g. the share of tablets, operating systems or form
+// factors in an access log.
+package report
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/knarz/gowurfl"
+)
+
+// Other is the value of the row summing up everything beyond the top N.
+const Other = "(other)"
+
+// Lookuper is what the Aggregator needs from the engine, usually a
+// *gowurfl.WURFL.
+type Lookuper interface {
+	LookupSnapshot(ua string) (*gowurfl.Snapshot, error)
+}
+
+// Config selects what is aggregated.
+type Config struct {
+	// Capabilities and VirtualCapabilities are the names to build a table
+	// for, each has to be loaded by the engine.
+	Capabilities        []string
+	VirtualCapabilities []string
+	// TopN limits every table to its N most frequent values, the remaining
+	// ones are summed up in a row with the value Other. 0 keeps all values.
+	TopN int
+	// Unique counts every distinct user agent once instead of as often as it
+	// was added.
+	Unique bool
+}
+
+// Aggregator collects user agents and looks each distinct one up once when
+// the report is built.
+type Aggregator struct {
+	l    Lookuper
+	c    Config
+	seen map[string]int
+}
+
+func New(l Lookuper, c Config) *Aggregator {
+	return &Aggregator{l: l, c: c, seen: make(map[string]int)}
+}
+
+// Add counts one occurrence of the user agent.
+func (a *Aggregator) Add(ua string) {
+	a.seen[ua]++
+}
+
+// ReadLines adds every non empty line of r as a user agent.
+func (a *Aggregator) ReadLines(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for s.Scan() {
+		if ua := strings.TrimSpace(s.Text()); ua != "" {
+			a.Add(ua)
+		}
+	}
+
+	return s.Err()
+}
+
+// Report is the result of an aggregation. The percentages are relative to the
+// user agents that were looked up successfully.
+type Report struct {
+	// Total is the number of user agents added, Unique the number of
+	// distinct ones and Failed the number that could not be looked up. Total
+	// and Failed count distinct user agents if Config.Unique is set.
+	Total  int     `json:"total"`
+	Unique int     `json:"unique"`
+	Failed int     `json:"failed"`
+	Tables []Table `json:"tables"`
+}
+
+// Table holds the counts of the values of one capability, ordered by count.
+type Table struct {
+	Name    string `json:"name"`
+	Virtual bool   `json:"virtual"`
+	Rows    []Row  `json:"rows"`
+}
+
+type Row struct {
+	Value   string  `json:"value"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+// Report looks up all distinct user agents added so far and aggregates the
+// configured capabilities.
+func (a *Aggregator) Report() *Report {
+	names := append(append([]string{}, a.c.Capabilities...), a.c.VirtualCapabilities...)
+	counts := make([]map[string]int, len(names))
+	for i := range counts {
+		counts[i] = make(map[string]int)
+	}
+
+	r := &Report{Unique: len(a.seen)}
+	matched := 0
+
+	for ua, n := range a.seen {
+		if a.c.Unique {
+			n = 1
+		}
+		r.Total += n
+
+		s, err := a.l.LookupSnapshot(ua)
+		if err != nil {
+			r.Failed += n
+			continue
+		}
+		matched += n
+
+		for i, name := range a.c.Capabilities {
+			v, _ := s.GetCapability(name)
+			counts[i][v] += n
+		}
+
+		for i, name := range a.c.VirtualCapabilities {
+			v, _ := s.GetVirtualCapability(name)
+			counts[len(a.c.Capabilities)+i][v] += n
+		}
+	}
+
+	for i, name := range names {
+		t := Table{Name: name, Virtual: i >= len(a.c.Capabilities)}
+		t.Rows = rows(counts[i], matched, a.c.TopN)
+		r.Tables = append(r.Tables, t)
+	}
+
+	return r
+}
+
+// rows sorts the counts by count and value and folds everything beyond topN
+// into a single Other row.
+func rows(counts map[string]int, total, topN int) []Row {
+	rs := make([]Row, 0, len(counts))
+	for v, c := range counts {
+		rs = append(rs, Row{Value: v, Count: c})
+	}
+
+	sort.Slice(rs, func(i, j int) bool {
+		if rs[i].Count != rs[j].Count {
+			return rs[i].Count > rs[j].Count
+		}
+		return rs[i].Value < rs[j].Value
+	})
+
+	if topN > 0 && len(rs) > topN {
+		other := Row{Value: Other}
+		for _, r := range rs[topN:] {
+			other.Count += r.Count
+		}
+		rs = append(rs[:topN], other)
+	}
+
+	for i := range rs {
+		if total > 0 {
+			rs[i].Percent = 100 * float64(rs[i].Count) / float64(total)
+		}
+	}
+
+	return rs
+}
+
+func percent(p float64) string {
+	return strconv.FormatFloat(p, 'f', 2, 64)
+}
+
+// WriteCSV writes all tables as a single CSV with the columns capability,
+// value, count and percent.
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"capability", "value", "count", "percent"})
+
+	for _, t := range r.Tables {
+		for _, row := range t.Rows {
+			cw.Write([]string{t.Name, row.Value, strconv.Itoa(row.Count), percent(row.Percent)})
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(r)
+}
+
+// WriteMarkdown writes a summary line followed by one table per capability.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "%d user agents, %d unique, %d failed lookups\n", r.Total, r.Unique, r.Failed)
+
+	for _, t := range r.Tables {
+		fmt.Fprintf(bw, "\n## %s\n\n", t.Name)
+		fmt.Fprintf(bw, "| value | count | percent |\n")
+		fmt.Fprintf(bw, "|-------|------:|--------:|\n")
+
+		for _, row := range t.Rows {
+			v := strings.ReplaceAll(row.Value, "|", `\|`)
+			if v == "" {
+				v = "(empty)"
+			}
+			fmt.Fprintf(bw, "| %s | %d | %s%% |\n", v, row.Count, percent(row.Percent))
+		}
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,113 @@
+package report
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/knarz/gowurfl"
+)
+
+type fakeLookuper map[string]*gowurfl.Snapshot
+
+func (f fakeLookuper) LookupSnapshot(ua string) (*gowurfl.Snapshot, error) {
+	s, ok := f[ua]
+	if !ok {
+		return nil, gowurfl.ErrorDeviceNotFound
+	}
+	return s, nil
+}
+
+func snapshot(tablet, os, ff string) *gowurfl.Snapshot {
+	return gowurfl.NewSnapshot("",
+		gowurfl.Capabilities{"is_tablet": tablet, "device_os": os},
+		gowurfl.Capabilities{"form_factor": ff},
+	)
+}
+
+var fake = fakeLookuper{
+	"ipad":    snapshot("true", "iOS", "Tablet"),
+	"iphone":  snapshot("false", "iOS", "Smartphone"),
+	"galaxy":  snapshot("false", "Android", "Smartphone"),
+	"desktop": snapshot("false", "Windows", "Desktop"),
+}
+
+const input = "ipad\niphone\n\niphone\ngalaxy\niphone\n  desktop  \nunknown\n"
+
+func testReport(t *testing.T, c Config) *Report {
+	a := New(fake, c)
+	if err := a.ReadLines(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+
+	return a.Report()
+}
+
+func TestReport(t *testing.T) {
+	tcs := []struct {
+		name   string
+		config Config
+		total  int
+		failed int
+		tables []Table
+	}{
+		{
+			name:   "weighted",
+			config: Config{Capabilities: []string{"is_tablet"}, VirtualCapabilities: []string{"form_factor"}, TopN: 2},
+			total:  7,
+			failed: 1,
+			tables: []Table{
+				{Name: "is_tablet", Rows: []Row{{"false", 5, 100 * 5.0 / 6}, {"true", 1, 100 * 1.0 / 6}}},
+				{Name: "form_factor", Virtual: true, Rows: []Row{{"Smartphone", 4, 100 * 4.0 / 6}, {"Desktop", 1, 100 * 1.0 / 6}, {Other, 1, 100 * 1.0 / 6}}},
+			},
+		},
+		{
+			name:   "unique",
+			config: Config{Capabilities: []string{"device_os"}, Unique: true},
+			total:  5,
+			failed: 1,
+			tables: []Table{
+				{Name: "device_os", Rows: []Row{{"iOS", 2, 50}, {"Android", 1, 25}, {"Windows", 1, 25}}},
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		r := testReport(t, tc.config)
+
+		if r.Total != tc.total || r.Unique != 5 || r.Failed != tc.failed {
+			t.Errorf("%s: got total %d, unique %d, failed %d, want %d, 5, %d", tc.name, r.Total, r.Unique, r.Failed, tc.total, tc.failed)
+		}
+
+		if !reflect.DeepEqual(r.Tables, tc.tables) {
+			t.Errorf("%s: tables differ\nwant: %v\nhave: %v", tc.name, tc.tables, r.Tables)
+		}
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	r := testReport(t, Config{VirtualCapabilities: []string{"form_factor"}, TopN: 1})
+
+	var b bytes.Buffer
+	if err := r.WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "capability,value,count,percent\nform_factor,Smartphone,4,66.67\nform_factor,(other),2,33.33\n"
+	if b.String() != want {
+		t.Errorf("WriteCSV()\nwant: %q\nhave: %q", want, b.String())
+	}
+
+	b.Reset()
+	if err := r.WriteMarkdown(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	want = "7 user agents, 5 unique, 1 failed lookups\n\n## form_factor\n\n" +
+		"| value | count | percent |\n|-------|------:|--------:|\n" +
+		"| Smartphone | 4 | 66.67% |\n| (other) | 2 | 33.33% |\n"
+	if b.String() != want {
+		t.Errorf("WriteMarkdown()\nwant: %q\nhave: %q", want, b.String())
+	}
+}
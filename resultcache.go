@@ -0,0 +1,309 @@
+package gowurfl
+
+import (
+	"container/list"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultResultCacheShards     = 16
+	defaultResultCacheMaxEntries = 10000
+)
+
+// ResultCacheConfig configures a ResultCache. Zero values select the defaults
+// for Shards and MaxEntries, a MaxBytes or TTL of zero disables the respective
+// limit.
+type ResultCacheConfig struct {
+	// Shards is the number of independently locked LRU lists.
+	Shards int
+	// MaxEntries is the maximum number of snapshots held by the cache.
+	MaxEntries int
+	// MaxBytes is the approximate maximum amount of memory the snapshots may
+	// occupy.
+	MaxBytes int64
+	// TTL is the time after which an entry is no longer returned.
+	TTL time.Duration
+}
+
+// ResultCacheStats holds the counters of a ResultCache.
+type ResultCacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+	Bytes     int64
+}
+
+// ResultCache is a sharded LRU cache for device snapshots living entirely in
+// Go. It sits in front of libwurfl and saves the cgo call and the string
+// conversions for every repeated lookup.
+// The cache is safe for concurrent use.
+type ResultCache struct {
+	shards []*resultCacheShard
+	ttl    time.Duration
+	now    func() time.Time
+
+	// generation is advanced by Purge. Entries are tagged with the generation
+	// they were added in, an engine only uses those of the generation of its
+	// Load and adds none once a newer engine was loaded.
+	generation atomic.Uint64
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+type resultCacheShard struct {
+	mu         sync.Mutex
+	ll         *list.List
+	items      map[string]*list.Element
+	bytes      int64
+	maxEntries int
+	maxBytes   int64
+}
+
+type resultCacheEntry struct {
+	key     string
+	gen     uint64
+	s       *Snapshot
+	size    int64
+	expires time.Time
+}
+
+// NewResultCache creates an empty ResultCache. The entry and byte limits are
+// split evenly between the shards. The byte limit is rounded up, so a limit
+// below the number of shards is not lost.
+func NewResultCache(cfg ResultCacheConfig) *ResultCache {
+	if cfg.Shards <= 0 {
+		cfg.Shards = defaultResultCacheShards
+	}
+
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = defaultResultCacheMaxEntries
+	}
+
+	if cfg.Shards > cfg.MaxEntries {
+		cfg.Shards = cfg.MaxEntries
+	}
+
+	c := &ResultCache{
+		shards: make([]*resultCacheShard, cfg.Shards),
+		ttl:    cfg.TTL,
+		now:    time.Now,
+	}
+
+	for i := range c.shards {
+		c.shards[i] = &resultCacheShard{
+			ll:         list.New(),
+			items:      make(map[string]*list.Element),
+			maxEntries: cfg.MaxEntries / cfg.Shards,
+			maxBytes:   (cfg.MaxBytes + int64(cfg.Shards) - 1) / int64(cfg.Shards),
+		}
+	}
+
+	return c
+}
+
+func (c *ResultCache) shard(key string) *resultCacheShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+
+	return c.shards[h.Sum32()%uint32(len(c.shards))]
+}
+
+// Get returns the snapshot stored for key, if any.
+func (c *ResultCache) Get(key string) (*Snapshot, bool) {
+	return c.get(key, c.generation.Load())
+}
+
+// get returns the snapshot stored for key in generation gen.
+func (c *ResultCache) get(key string, gen uint64) (*Snapshot, bool) {
+	sh := c.shard(key)
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	el, ok := sh.items[key]
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	e := el.Value.(*resultCacheEntry)
+	if e.gen != gen {
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	if c.ttl > 0 && c.now().After(e.expires) {
+		sh.remove(el)
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	sh.ll.MoveToFront(el)
+	c.hits.Add(1)
+
+	return e.s, true
+}
+
+// Add stores the snapshot for key and evicts the least recently used entries
+// until the shard is within its limits again.
+func (c *ResultCache) Add(key string, s *Snapshot) {
+	c.add(key, s, c.generation.Load())
+}
+
+// add stores the snapshot for key unless gen is outdated, i.e. the snapshot
+// stems from an engine replaced since.
+func (c *ResultCache) add(key string, s *Snapshot, gen uint64) {
+	sh := c.shard(key)
+	e := &resultCacheEntry{key: key, gen: gen, s: s, size: s.size() + int64(len(key))}
+
+	if c.ttl > 0 {
+		e.expires = c.now().Add(c.ttl)
+	}
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	// purge advances the generation before clearing the shards, so an
+	// outdated entry is either refused here or cleared afterwards
+	if gen != c.generation.Load() {
+		return
+	}
+
+	if el, ok := sh.items[key]; ok {
+		sh.remove(el)
+	}
+
+	sh.items[key] = sh.ll.PushFront(e)
+	sh.bytes += e.size
+
+	for sh.ll.Len() > 1 && (sh.ll.Len() > sh.maxEntries || (sh.maxBytes > 0 && sh.bytes > sh.maxBytes)) {
+		sh.remove(sh.ll.Back())
+		c.evictions.Add(1)
+	}
+}
+
+// Purge removes all entries from the cache. The counters are kept.
+func (c *ResultCache) Purge() {
+	c.purge()
+}
+
+// purge removes all entries and returns the new generation.
+func (c *ResultCache) purge() uint64 {
+	gen := c.generation.Add(1)
+
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		sh.ll.Init()
+		sh.items = make(map[string]*list.Element)
+		sh.bytes = 0
+		sh.mu.Unlock()
+	}
+
+	return gen
+}
+
+// Stats returns the current counters of the cache.
+func (c *ResultCache) Stats() ResultCacheStats {
+	st := ResultCacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+	}
+
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		st.Entries += sh.ll.Len()
+		st.Bytes += sh.bytes
+		sh.mu.Unlock()
+	}
+
+	return st
+}
+
+func (sh *resultCacheShard) remove(el *list.Element) {
+	e := sh.ll.Remove(el).(*resultCacheEntry)
+	delete(sh.items, e.key)
+	sh.bytes -= e.size
+}
+
+// normalizeUserAgent trims the user agent and collapses runs of white space
+// so that trivially different spellings share a cache entry.
+func normalizeUserAgent(ua string) string {
+	return strings.Join(strings.Fields(ua), " ")
+}
+
+// SetResultCache attaches a ResultCache to the engine which is then used by
+// LookupSnapshot. The same cache may be attached to a freshly loaded engine
+// replacing an older one, e.g. by a Reloader. Load purges it in that case, and
+// the replaced engine, while still serving, neither uses the entries of the new
+// one nor adds its own.
+// Passing nil detaches the cache.
+func (w *WURFL) SetResultCache(c *ResultCache) {
+	w.cache = c
+	if c != nil {
+		w.cacheGen.Store(c.generation.Load())
+	}
+}
+
+// GetResultCache returns the ResultCache attached to the engine, nil if there
+// is none.
+func (w *WURFL) GetResultCache() *ResultCache {
+	return w.cache
+}
+
+// cachedSnapshot returns the snapshot cached for key by the engine, if any.
+func (w *WURFL) cachedSnapshot(key string) (*Snapshot, bool) {
+	if w.cache == nil {
+		return nil, false
+	}
+
+	s, ok := w.cache.get(key, w.cacheGen.Load())
+	if !ok {
+		return nil, false
+	}
+
+	return s.withRecorder(w.recorder), true
+}
+
+// LookupSnapshot looks up the user agent and returns a snapshot of the
+// matching device. If a ResultCache is attached, it is consulted first and
+// libwurfl is only queried on a miss. Both use the user agent with white space
+// trimmed and collapsed, so the result does not depend on whether it was
+// cached.
+func (w *WURFL) LookupSnapshot(ua string) (*Snapshot, error) {
+	key := normalizeUserAgent(ua)
+
+	if s, ok := w.cachedSnapshot(key); ok {
+		return s, nil
+	}
+
+	return w.lookupSnapshot(key, func() (*Device, error) { return w.LookupUserAgent(key) })
+}
+
+// lookupSnapshot queries libwurfl through lookup and stores the result under
+// key.
+func (w *WURFL) lookupSnapshot(key string, lookup func() (*Device, error)) (*Snapshot, error) {
+	d, err := lookup()
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	s, err := d.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	if w.cache != nil {
+		w.cache.add(key, s, w.cacheGen.Load())
+	}
+
+	return s, nil
+}
@@ -0,0 +1,224 @@
+package gowurfl
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func testSnapshot(id string) *Snapshot {
+	return &Snapshot{
+		id:                  id,
+		capabilities:        Capabilities{"brand_name": "generic"},
+		virtualCapabilities: Capabilities{"is_mobile": "false"},
+	}
+}
+
+func TestResultCacheGetAdd(t *testing.T) {
+	c := NewResultCache(ResultCacheConfig{})
+
+	if _, ok := c.Get("ua"); ok {
+		t.Errorf("Get() on empty cache returned an entry")
+	}
+
+	c.Add("ua", testSnapshot("generic"))
+
+	s, ok := c.Get("ua")
+	if !ok {
+		t.Fatalf("Get() did not return the added entry")
+	}
+
+	if s.id != "generic" {
+		t.Errorf("Get() expected id %q but got %q", "generic", s.id)
+	}
+
+	st := c.Stats()
+	if st.Hits != 1 || st.Misses != 1 || st.Entries != 1 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+}
+
+func TestResultCacheEvictsEntries(t *testing.T) {
+	c := NewResultCache(ResultCacheConfig{Shards: 1, MaxEntries: 2})
+
+	c.Add("a", testSnapshot("a"))
+	c.Add("b", testSnapshot("b"))
+	c.Get("a")
+	c.Add("c", testSnapshot("c"))
+
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("least recently used entry was not evicted")
+	}
+
+	for _, k := range []string{"a", "c"} {
+		if _, ok := c.Get(k); !ok {
+			t.Errorf("entry %q should still be cached", k)
+		}
+	}
+
+	if st := c.Stats(); st.Evictions != 1 || st.Entries != 2 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+}
+
+func TestResultCacheEvictsBytes(t *testing.T) {
+	s := testSnapshot("generic")
+	max := 3 * (s.size() + 2)
+	c := NewResultCache(ResultCacheConfig{Shards: 1, MaxBytes: max})
+
+	for i := 0; i < 10; i++ {
+		c.Add(strconv.Itoa(i+10), testSnapshot("generic"))
+	}
+
+	st := c.Stats()
+	if st.Bytes > max {
+		t.Errorf("cache holds %d bytes, limit is %d", st.Bytes, max)
+	}
+
+	if st.Entries != 3 {
+		t.Errorf("expected 3 entries but got %d", st.Entries)
+	}
+}
+
+func TestResultCacheSmallMaxBytes(t *testing.T) {
+	c := NewResultCache(ResultCacheConfig{Shards: 4, MaxBytes: 2})
+
+	for i := 0; i < 10; i++ {
+		c.Add(strconv.Itoa(i+10), testSnapshot("generic"))
+	}
+
+	// every shard keeps its newest entry, even if it exceeds the limit
+	if st := c.Stats(); st.Entries > 4 {
+		t.Errorf("a MaxBytes below Shards disabled the limit, cache holds %d entries", st.Entries)
+	}
+}
+
+func TestResultCacheTTL(t *testing.T) {
+	now := time.Now()
+	c := NewResultCache(ResultCacheConfig{TTL: time.Minute})
+	c.now = func() time.Time { return now }
+
+	c.Add("ua", testSnapshot("generic"))
+
+	if _, ok := c.Get("ua"); !ok {
+		t.Errorf("entry expired too early")
+	}
+
+	now = now.Add(2 * time.Minute)
+
+	if _, ok := c.Get("ua"); ok {
+		t.Errorf("entry did not expire")
+	}
+
+	if st := c.Stats(); st.Entries != 0 {
+		t.Errorf("expired entry was not removed: %+v", st)
+	}
+}
+
+func TestResultCachePurge(t *testing.T) {
+	c := NewResultCache(ResultCacheConfig{})
+	c.Add("ua", testSnapshot("generic"))
+	c.Purge()
+
+	if st := c.Stats(); st.Entries != 0 || st.Bytes != 0 {
+		t.Errorf("Purge() left entries behind: %+v", st)
+	}
+}
+
+func TestResultCacheShared(t *testing.T) {
+	c := NewResultCache(ResultCacheConfig{})
+
+	old := &WURFL{}
+	old.SetResultCache(c)
+	c.add("old", testSnapshot("old"), old.cacheGen.Load())
+
+	// what Load of the replacing engine does
+	cur := &WURFL{}
+	cur.SetResultCache(c)
+	cur.cacheGen.Store(c.purge())
+	c.add("cur", testSnapshot("cur"), cur.cacheGen.Load())
+
+	// the replaced engine keeps serving until it is closed
+	c.add("stale", testSnapshot("stale"), old.cacheGen.Load())
+
+	if _, ok := cur.cachedSnapshot("stale"); ok {
+		t.Errorf("the replaced engine added to the purged cache")
+	}
+
+	if _, ok := cur.cachedSnapshot("old"); ok {
+		t.Errorf("the purged entry of the replaced engine is still cached")
+	}
+
+	if _, ok := old.cachedSnapshot("cur"); ok {
+		t.Errorf("the replaced engine used an entry of its replacement")
+	}
+
+	if s, ok := cur.cachedSnapshot("cur"); !ok || s.id != "cur" {
+		t.Errorf("the entry of the current engine is not cached")
+	}
+}
+
+func TestSnapshotCopies(t *testing.T) {
+	caps := Capabilities{"brand_name": "Apple"}
+	s := NewSnapshot("apple_iphone_ver1", caps, nil)
+	caps["brand_name"] = "Samsung"
+
+	got, err := s.GetCapabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got["brand_name"] = "Nokia"
+
+	if v, _ := s.GetCapability("brand_name"); v != "Apple" {
+		t.Errorf("snapshot was modified through its maps, brand_name is %q", v)
+	}
+
+	if vcaps, _ := s.GetVirtualCapabilities(); vcaps != nil {
+		t.Errorf("GetVirtualCapabilities() expected nil but got %v", vcaps)
+	}
+}
+
+func TestNormalizeUserAgent(t *testing.T) {
+	tcs := []struct {
+		in, out string
+	}{
+		{"Dillo/2.0", "Dillo/2.0"},
+		{"  Dillo/2.0 ", "Dillo/2.0"},
+		{"NetSurf/2.0  (RISC OS;\tarmv5l)", "NetSurf/2.0 (RISC OS; armv5l)"},
+	}
+
+	for _, tc := range tcs {
+		if n := normalizeUserAgent(tc.in); n != tc.out {
+			t.Errorf("normalizeUserAgent(%q) expected %q but got %q", tc.in, tc.out, n)
+		}
+	}
+}
+
+func TestLookupSnapshot(t *testing.T) {
+	w := testNewEngine(t)
+	defer w.Close()
+
+	c := NewResultCache(ResultCacheConfig{})
+	w.SetResultCache(c)
+	testLoadRepository(rootFile, w, t)
+
+	for _, ua := range uas {
+		s, err := w.LookupSnapshot(ua)
+		if err != nil {
+			t.Fatalf("LookupSnapshot(%q) failed with: %s", ua, err)
+		}
+
+		cached, err := w.LookupSnapshot(ua)
+		if err != nil {
+			t.Fatalf("LookupSnapshot(%q) failed with: %s", ua, err)
+		}
+
+		if cached != s {
+			t.Errorf("LookupSnapshot(%q) did not return the cached snapshot", ua)
+		}
+	}
+
+	if st := c.Stats(); st.Hits < uint64(len(uas)) {
+		t.Errorf("expected at least %d hits but got %d", len(uas), st.Hits)
+	}
+}
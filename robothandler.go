@@ -0,0 +1,131 @@
+package gowurfl
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+type robotOptions struct {
+	reject             bool
+	status             int
+	allowSearchEngines bool
+	verify             bool
+	resolver           Resolver
+	header             string
+}
+
+// RobotOption configures RobotHandler.
+type RobotOption func(*robotOptions)
+
+// RejectRobots answers requests of robots with status instead of passing them
+// on. An invalid status is replaced by http.StatusForbidden.
+func RejectRobots(status int) RobotOption {
+	if status < 100 || status > 999 {
+		status = http.StatusForbidden
+	}
+
+	return func(o *robotOptions) {
+		o.reject = true
+		o.status = status
+	}
+}
+
+// AllowSearchEngines exempts known search engine crawlers from RejectRobots.
+// Together with VerifyRobots only verified crawlers are exempt.
+func AllowSearchEngines() RobotOption {
+	return func(o *robotOptions) {
+		o.allowSearchEngines = true
+	}
+}
+
+// VerifyRobots verifies search engine crawlers by the remote address of the
+// request, see BotInfo.Verify. This costs two DNS lookups for every request of
+// a crawler, r may be a caching resolver. A nil r uses net.DefaultResolver.
+// Crawlers whose verification fails are not verified, the error is logged at
+// the Errors level, see SetLogger.
+func VerifyRobots(r Resolver) RobotOption {
+	return func(o *robotOptions) {
+		o.verify = true
+		o.resolver = r
+	}
+}
+
+// TagRobots sets the request header name to the name of the robot before
+// passing the request on, e.g. for proxied backends. The header is removed
+// from all other requests, so clients cannot set it themselves.
+func TagRobots(name string) RobotOption {
+	return func(o *robotOptions) {
+		o.header = name
+	}
+}
+
+type botInfoKey struct{}
+
+// BotInfoFromContext returns the BotInfo RobotHandler stored in the context of
+// a robot's request, nil for other requests.
+func BotInfoFromContext(ctx context.Context) *BotInfo {
+	bi, _ := ctx.Value(botInfoKey{}).(*BotInfo)
+	return bi
+}
+
+// RobotHandler looks up the device of every request and tags robots by storing
+// their BotInfo in the request context, see BotInfoFromContext. The options
+// decide whether robots are rejected as well. Requests whose lookup fails are
+// passed on untagged. Lookups go through LookupRequestSnapshot, so attaching a
+// ResultCache is recommended.
+func (w *WURFL) RobotHandler(next http.Handler, opts ...RobotOption) http.Handler {
+	var o robotOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if o.header != "" {
+			r.Header.Del(o.header)
+		}
+
+		bi := w.requestBotInfo(r, &o)
+		if bi == nil {
+			next.ServeHTTP(rw, r)
+			return
+		}
+
+		allowed := o.allowSearchEngines && bi.IsSearchEngine() && (bi.Verified || !o.verify)
+		if o.reject && !allowed {
+			http.Error(rw, http.StatusText(o.status), o.status)
+			return
+		}
+
+		if o.header != "" {
+			r.Header.Set(o.header, bi.Name)
+		}
+
+		next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), botInfoKey{}, bi)))
+	})
+}
+
+func (w *WURFL) requestBotInfo(r *http.Request, o *robotOptions) *BotInfo {
+	s, err := w.LookupRequestSnapshot(r)
+	if err != nil {
+		return nil
+	}
+
+	bi, err := botInfo(s.id, s.GetVirtualCapability)
+	if err != nil || bi == nil {
+		return nil
+	}
+
+	if o.verify && bi.IsSearchEngine() {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+
+		if err := bi.Verify(r.Context(), o.resolver, ip); err != nil {
+			w.log(w.levels.Errors, "failed to verify robot", "id", bi.ID, "search_engine", bi.SearchEngine, "ip", ip, "error", err)
+		}
+	}
+
+	return bi
+}
@@ -0,0 +1,249 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lex splits an expression into tokens. Numbers may contain dots so that
+// versions like 10.3.1 are a single token.
+func lex(s string) ([]token, error) {
+	var toks []token
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case c == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				b.WriteByte(s[j])
+			}
+			if j == len(s) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			toks = append(toks, token{tokString, b.String(), i})
+			i = j + 1
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{tokNumber, s[i:j], i})
+			i = j
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i
+			for j < len(s) && (s[j] == '_' || s[j] >= 'a' && s[j] <= 'z' || s[j] >= 'A' && s[j] <= 'Z' || s[j] >= '0' && s[j] <= '9') {
+				j++
+			}
+			toks = append(toks, token{tokIdent, s[i:j], i})
+			i = j
+		default:
+			op := ""
+			for _, o := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!"} {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+			toks = append(toks, token{tokOp, op, i})
+			i += len(op)
+		}
+	}
+
+	return append(toks, token{tokEOF, "", len(s)}), nil
+}
+
+// parser is a recursive descent parser for
+//
+//	or      = and { "||" and }
+//	and     = not { "&&" not }
+//	not     = "!" not | compare
+//	compare = operand [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) operand ]
+//	operand = ident | string | number | "true" | "false" | "(" or ")"
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func parse(s string) (node, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{toks: toks}
+
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+	}
+
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOp(ops ...string) bool {
+	t := p.peek()
+	if t.kind != tokOp {
+		return false
+	}
+
+	for _, o := range ops {
+		if t.text == o {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *parser) or() (node, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOp("||") {
+		p.next()
+
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l = &orNode{l, r}
+	}
+
+	return l, nil
+}
+
+func (p *parser) and() (node, error) {
+	l, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOp("&&") {
+		p.next()
+
+		r, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		l = &andNode{l, r}
+	}
+
+	return l, nil
+}
+
+func (p *parser) not() (node, error) {
+	if p.isOp("!") {
+		p.next()
+
+		n, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{n}, nil
+	}
+
+	return p.compare()
+}
+
+func (p *parser) compare() (node, error) {
+	l, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.isOp("==", "!=", "<", "<=", ">", ">=") {
+		return l, nil
+	}
+	op := p.next().text
+
+	r, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	return &compareNode{op, l, r}, nil
+}
+
+func (p *parser) operand() (node, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokIdent:
+		switch t.text {
+		case "true", "false":
+			return &literalNode{value: t.text}, nil
+		}
+		return &identNode{name: t.text}, nil
+	case tokString:
+		return &literalNode{value: t.text}, nil
+	case tokNumber:
+		return &literalNode{value: t.text, number: true}, nil
+	case tokLParen:
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+
+		if t := p.next(); t.kind != tokRParen {
+			return nil, fmt.Errorf("expected ) at %d", t.pos)
+		}
+		return n, nil
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
@@ -0,0 +1,425 @@
+// Package rules evaluates small boolean expressions over capabilities, e.g.
+//
+//	is_tablet && resolution_width >= 1024
+//	advertised_device_os == "iOS" && os_version < 13
+//
+// Identifiers name capabilities or virtual capabilities, or one of the
+// Aliases. An identifier on its own must have the value "true" or "false".
+// Ordering comparisons and comparisons with a number compare versions as
+// gowurfl.Version does, so 4.4.2 < 5 holds; unknown versions make them false.
+// Other comparisons compare strings.
+//
+// Expressions are parsed once, checked against an engine with Bind and then
+// evaluated against any number of devices or snapshots, also concurrently.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/knarz/gowurfl"
+)
+
+// Aliases are short names for frequently used virtual capabilities.
+var Aliases = map[string]string{
+	"os":              "advertised_device_os",
+	"os_version":      "advertised_device_os_version",
+	"browser":         "advertised_browser",
+	"browser_version": "advertised_browser_version",
+}
+
+// Checker is used by Bind to look up the names used in expressions, usually a
+// loaded *gowurfl.WURFL.
+type Checker interface {
+	HasCapability(name string) bool
+	HasVirtualCapability(name string) bool
+}
+
+// Source provides the values expressions are evaluated against, e.g. a
+// *gowurfl.Device or a *gowurfl.Snapshot.
+type Source interface {
+	GetCapability(name string) (string, error)
+	GetVirtualCapability(name string) (string, error)
+}
+
+// Expr is a parsed expression.
+type Expr struct {
+	src  string
+	root node
+}
+
+// ParseExpr parses an expression without checking the names it uses.
+func ParseExpr(s string) (*Expr, error) {
+	n, err := parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", s, err)
+	}
+
+	return &Expr{src: s, root: n}, nil
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Capabilities returns the names of the capabilities and virtual capabilities
+// the expression uses, with aliases resolved.
+func (e *Expr) Capabilities() []string {
+	seen := make(map[string]bool)
+	e.root.idents(func(n *identNode) { seen[n.capability()] = true })
+
+	names := make([]string, 0, len(seen))
+	for n := range seen {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Bind resolves every name to a capability or a virtual capability known to c.
+// All unknown names are returned joined, wrapping
+// gowurfl.ErrorCapabilityNotFound. Unbound expressions can be evaluated as
+// well, names are then tried as capability first and virtual capability
+// second on every evaluation.
+func (e *Expr) Bind(c Checker) error {
+	var errs []error
+
+	e.root.idents(func(n *identNode) {
+		switch name := n.capability(); {
+		case c.HasCapability(name):
+			n.kind = identCapability
+		case c.HasVirtualCapability(name):
+			n.kind = identVirtual
+		default:
+			errs = append(errs, fmt.Errorf("%q: %w: %q", e.src, gowurfl.ErrorCapabilityNotFound, name))
+		}
+	})
+
+	return errors.Join(errs...)
+}
+
+// Eval evaluates the expression against src.
+func (e *Expr) Eval(src Source) (bool, error) {
+	return e.root.evalBool(src)
+}
+
+// Rule names the variant chosen if its expression is true.
+type Rule struct {
+	Name string
+	Expr string
+}
+
+// Set is an ordered list of rules.
+type Set struct {
+	names []string
+	exprs []*Expr
+}
+
+// Parse parses the rules without checking the names they use. Request the
+// capabilities listed by Capabilities before loading the engine and Bind the
+// set afterwards.
+func Parse(rules ...Rule) (*Set, error) {
+	s := &Set{}
+
+	var errs []error
+	for _, r := range rules {
+		e, err := ParseExpr(r.Expr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Name, err))
+			continue
+		}
+
+		s.names = append(s.names, r.Name)
+		s.exprs = append(s.exprs, e)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return s, nil
+}
+
+// Compile parses the rules and binds them to c.
+func Compile(c Checker, rules ...Rule) (*Set, error) {
+	s, err := Parse(rules...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Bind(c); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Capabilities returns the names used by all rules, e.g. for
+// gowurfl.WithCapabilities.
+func (s *Set) Capabilities() []string {
+	seen := make(map[string]bool)
+	var names []string
+
+	for _, e := range s.exprs {
+		for _, n := range e.Capabilities() {
+			if !seen[n] {
+				seen[n] = true
+				names = append(names, n)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Bind binds all rules, see Expr.Bind.
+func (s *Set) Bind(c Checker) error {
+	var errs []error
+	for i, e := range s.exprs {
+		if err := e.Bind(c); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.names[i], err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Match returns the name of the first rule whose expression is true for src,
+// "" if there is none.
+func (s *Set) Match(src Source) (string, error) {
+	for i, e := range s.exprs {
+		ok, err := e.Eval(src)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", s.names[i], err)
+		}
+
+		if ok {
+			return s.names[i], nil
+		}
+	}
+
+	return "", nil
+}
+
+type node interface {
+	evalBool(src Source) (bool, error)
+	evalValue(src Source) (string, error)
+	idents(f func(*identNode))
+}
+
+type identKind int
+
+const (
+	identUnbound identKind = iota
+	identCapability
+	identVirtual
+)
+
+type identNode struct {
+	name string
+	kind identKind
+}
+
+// capability returns the name with aliases resolved.
+func (n *identNode) capability() string {
+	if a, ok := Aliases[n.name]; ok {
+		return a
+	}
+	return n.name
+}
+
+func (n *identNode) evalValue(src Source) (string, error) {
+	name := n.capability()
+
+	switch n.kind {
+	case identCapability:
+		return src.GetCapability(name)
+	case identVirtual:
+		return src.GetVirtualCapability(name)
+	}
+
+	if v, err := src.GetCapability(name); err == nil {
+		return v, nil
+	}
+	return src.GetVirtualCapability(name)
+}
+
+func (n *identNode) evalBool(src Source) (bool, error) {
+	v, err := n.evalValue(src)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", n.name, err)
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s: %q is not a boolean", n.name, v)
+	}
+
+	return b, nil
+}
+
+func (n *identNode) idents(f func(*identNode)) {
+	f(n)
+}
+
+type literalNode struct {
+	value  string
+	number bool
+}
+
+func (n *literalNode) evalValue(Source) (string, error) {
+	return n.value, nil
+}
+
+func (n *literalNode) evalBool(Source) (bool, error) {
+	switch n.value {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+
+	return false, fmt.Errorf("%q is not a boolean", n.value)
+}
+
+func (n *literalNode) idents(func(*identNode)) {}
+
+type compareNode struct {
+	op   string
+	l, r node
+}
+
+func (n *compareNode) evalBool(src Source) (bool, error) {
+	l, err := n.l.evalValue(src)
+	if err != nil {
+		return false, err
+	}
+
+	r, err := n.r.evalValue(src)
+	if err != nil {
+		return false, err
+	}
+
+	if !n.versions() {
+		switch n.op {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		}
+	}
+
+	lv, rv := gowurfl.ParseVersion(l), gowurfl.ParseVersion(r)
+	if !lv.Known() || !rv.Known() {
+		return n.op == "!=", nil
+	}
+
+	c := lv.Compare(rv)
+
+	switch n.op {
+	case "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+// versions reports whether the operands are compared as versions.
+func (n *compareNode) versions() bool {
+	if n.op != "==" && n.op != "!=" {
+		return true
+	}
+
+	for _, o := range []node{n.l, n.r} {
+		if l, ok := o.(*literalNode); ok && l.number {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (n *compareNode) evalValue(Source) (string, error) {
+	return "", errors.New("a comparison is not a value")
+}
+
+func (n *compareNode) idents(f func(*identNode)) {
+	n.l.idents(f)
+	n.r.idents(f)
+}
+
+type andNode struct {
+	l, r node
+}
+
+func (n *andNode) evalBool(src Source) (bool, error) {
+	l, err := n.l.evalBool(src)
+	if err != nil || !l {
+		return false, err
+	}
+
+	return n.r.evalBool(src)
+}
+
+func (n *andNode) evalValue(Source) (string, error) {
+	return "", errors.New("&& is not a value")
+}
+
+func (n *andNode) idents(f func(*identNode)) {
+	n.l.idents(f)
+	n.r.idents(f)
+}
+
+type orNode struct {
+	l, r node
+}
+
+func (n *orNode) evalBool(src Source) (bool, error) {
+	l, err := n.l.evalBool(src)
+	if err != nil || l {
+		return l, err
+	}
+
+	return n.r.evalBool(src)
+}
+
+func (n *orNode) evalValue(Source) (string, error) {
+	return "", errors.New("|| is not a value")
+}
+
+func (n *orNode) idents(f func(*identNode)) {
+	n.l.idents(f)
+	n.r.idents(f)
+}
+
+type notNode struct {
+	n node
+}
+
+func (n *notNode) evalBool(src Source) (bool, error) {
+	b, err := n.n.evalBool(src)
+	if err != nil {
+		return false, err
+	}
+
+	return !b, nil
+}
+
+func (n *notNode) evalValue(Source) (string, error) {
+	return "", errors.New("! is not a value")
+}
+
+func (n *notNode) idents(f func(*identNode)) {
+	n.n.idents(f)
+}
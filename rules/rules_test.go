@@ -0,0 +1,172 @@
+package rules
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/knarz/gowurfl"
+)
+
+var (
+	_ Checker = (*gowurfl.WURFL)(nil)
+	_ Source  = (*gowurfl.Device)(nil)
+	_ Source  = (*gowurfl.Snapshot)(nil)
+)
+
+type fakeChecker struct {
+	caps  []string
+	vcaps []string
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (c fakeChecker) HasCapability(name string) bool        { return contains(c.caps, name) }
+func (c fakeChecker) HasVirtualCapability(name string) bool { return contains(c.vcaps, name) }
+
+var checker = fakeChecker{
+	caps:  []string{"is_tablet", "resolution_width", "brand_name"},
+	vcaps: []string{"advertised_device_os", "advertised_device_os_version", "is_mobile"},
+}
+
+var (
+	ipad = gowurfl.NewSnapshot("",
+		gowurfl.Capabilities{"is_tablet": "true", "resolution_width": "2048", "brand_name": "Apple"},
+		gowurfl.Capabilities{"advertised_device_os": "iOS", "advertised_device_os_version": "12.4.1", "is_mobile": "true"},
+	)
+	android = gowurfl.NewSnapshot("",
+		gowurfl.Capabilities{"is_tablet": "false", "resolution_width": "540", "brand_name": "Lenovo"},
+		gowurfl.Capabilities{"advertised_device_os": "Android", "advertised_device_os_version": "4.4.2", "is_mobile": "true"},
+	)
+	desktop = gowurfl.NewSnapshot("",
+		gowurfl.Capabilities{"is_tablet": "false", "resolution_width": "800", "brand_name": "generic web browser"},
+		gowurfl.Capabilities{"advertised_device_os": "Mac OS X", "advertised_device_os_version": "10_11_4", "is_mobile": "false"},
+	)
+)
+
+func TestEval(t *testing.T) {
+	tcs := []struct {
+		expr string
+		src  *gowurfl.Snapshot
+		want bool
+	}{
+		{"is_tablet && resolution_width >= 1024", ipad, true},
+		{"is_tablet && resolution_width >= 1024", android, false},
+		{`advertised_device_os == "iOS" && os_version < 13`, ipad, true},
+		{`os == "Android" && os_version < 5`, android, true},
+		{`os == "Android" && os_version < 4.4`, android, false},
+		{`os_version >= 10.9`, desktop, true},
+		{"!is_mobile || brand_name == \"Apple\"", ipad, true},
+		{"!is_mobile || brand_name == \"Apple\"", android, false},
+		{"!(is_mobile && is_tablet)", desktop, true},
+		{"resolution_width == 800", desktop, true},
+		{"resolution_width == 800.0", desktop, true},
+		{`brand_name != "Apple"`, desktop, true},
+		{"true && !false", desktop, true},
+	}
+
+	for _, tc := range tcs {
+		e, err := ParseExpr(tc.expr)
+		if err != nil {
+			t.Errorf("ParseExpr(%q) failed with: %s", tc.expr, err)
+			continue
+		}
+
+		for _, bound := range []bool{false, true} {
+			if bound {
+				if err := e.Bind(checker); err != nil {
+					t.Errorf("Bind() for %q failed with: %s", tc.expr, err)
+					continue
+				}
+			}
+
+			got, err := e.Eval(tc.src)
+			if err != nil || got != tc.want {
+				t.Errorf("Eval(%q) bound %v returned %v, %v, want %v", tc.expr, bound, got, err, tc.want)
+			}
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"is_tablet &&",
+		"(is_tablet",
+		`brand_name == "Apple`,
+		"is_tablet is_mobile",
+		"resolution_width => 5",
+		"a == == b",
+	} {
+		if _, err := ParseExpr(expr); err == nil {
+			t.Errorf("ParseExpr(%q) expected to fail but did not", expr)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	for _, expr := range []string{
+		"brand_name",
+		"unknown_capability == 1",
+		`"yes"`,
+	} {
+		e, err := ParseExpr(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := e.Eval(ipad); err == nil {
+			t.Errorf("Eval(%q) expected to fail but did not", expr)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	rules := []Rule{
+		{"tablet", "is_tablet && resolution_width >= 1024"},
+		{"lite", `os == "Android" && os_version < 5`},
+		{"mobile", "is_mobile"},
+	}
+
+	s, err := Parse(rules...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"advertised_device_os", "advertised_device_os_version", "is_mobile", "is_tablet", "resolution_width"}
+	if c := s.Capabilities(); !reflect.DeepEqual(c, want) {
+		t.Errorf("Capabilities()\nwant: %v\nhave: %v", want, c)
+	}
+
+	if err := s.Bind(checker); err != nil {
+		t.Fatal(err)
+	}
+
+	for src, want := range map[*gowurfl.Snapshot]string{ipad: "tablet", android: "lite", desktop: ""} {
+		if v, err := s.Match(src); err != nil || v != want {
+			t.Errorf("Match() returned %q, %v, want %q", v, err, want)
+		}
+	}
+}
+
+func TestCompileUnknownCapability(t *testing.T) {
+	_, err := Compile(checker, Rule{"tv", "is_smarttv || resolution_width > 1920"}, Rule{"bot", "is_bot"})
+
+	if !errors.Is(err, gowurfl.ErrorCapabilityNotFound) {
+		t.Fatalf("Compile() returned %v, want ErrorCapabilityNotFound", err)
+	}
+
+	for _, name := range []string{"is_smarttv", "is_bot"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not mention %q", err, name)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package gowurfl
+
+import "maps"
+
+// Snapshot is a copy of a device living entirely on the Go side. Unlike a
+// Device it holds no reference into libwurfl, it does not have to be closed
+// and it can be shared freely between goroutines. The values are only
+// accessible through the getters, so snapshots held by a ResultCache cannot be
+// modified, and they record into the CapabilityRecorder of the engine that
+// returned them, even when taken from a ResultCache shared with other engines.
+type Snapshot struct {
+	id string
+
+	capabilities        Capabilities
+	virtualCapabilities Capabilities
+	recorder            *CapabilityRecorder
+}
+
+// NewSnapshot returns a snapshot of a device with the given values, e.g. to
+// stand in for a looked up device in tests. The maps are copied.
+func NewSnapshot(id string, caps, vcaps Capabilities) *Snapshot {
+	return &Snapshot{id: id, capabilities: maps.Clone(caps), virtualCapabilities: maps.Clone(vcaps)}
+}
+
+// Snapshot copies the id, all loaded capabilities and all virtual capabilities
+// of the device into a Snapshot.
+func (d *Device) Snapshot() (*Snapshot, error) {
+	id, err := d.GetID()
+	if err != nil {
+		return nil, err
+	}
+
+	caps, err := d.capabilities()
+	if err != nil {
+		return nil, err
+	}
+
+	vcaps, err := d.virtualCapabilities()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{id: id, capabilities: caps, virtualCapabilities: vcaps, recorder: d.recorder}, nil
+}
+
+// withRecorder returns s recording into r. A copy is made if s records
+// elsewhere, it shares the values, which are never modified.
+func (s *Snapshot) withRecorder(r *CapabilityRecorder) *Snapshot {
+	if s.recorder == r {
+		return s
+	}
+
+	c := *s
+	c.recorder = r
+
+	return &c
+}
+
+func (s *Snapshot) GetID() (string, error) {
+	return s.id, nil
+}
+
+func (s *Snapshot) GetCapability(name string) (string, error) {
+	s.recorder.capability(name)
+
+	v, ok := s.capabilities[name]
+	if !ok {
+		return "", ErrorCapabilityNotFound
+	}
+
+	return v, nil
+}
+
+func (s *Snapshot) GetVirtualCapability(name string) (string, error) {
+	s.recorder.virtualCapability(name)
+
+	v, ok := s.virtualCapabilities[name]
+	if !ok {
+		return "", ErrorVirtualCapabilityNotFound
+	}
+
+	return v, nil
+}
+
+// GetCapabilities returns a copy of all capabilities of the snapshot. All of
+// them are recorded as used.
+func (s *Snapshot) GetCapabilities() (Capabilities, error) {
+	for name := range s.capabilities {
+		s.recorder.capability(name)
+	}
+
+	return maps.Clone(s.capabilities), nil
+}
+
+// GetVirtualCapabilities returns a copy of all virtual capabilities of the
+// snapshot. All of them are recorded as used.
+func (s *Snapshot) GetVirtualCapabilities() (Capabilities, error) {
+	for name := range s.virtualCapabilities {
+		s.recorder.virtualCapability(name)
+	}
+
+	return maps.Clone(s.virtualCapabilities), nil
+}
+
+// size returns a rough estimate of the memory held by the snapshot in bytes.
+func (s *Snapshot) size() int64 {
+	// the constants approximate the struct and per map entry overhead
+	n := int64(64 + len(s.id))
+
+	for k, v := range s.capabilities {
+		n += int64(32 + len(k) + len(v))
+	}
+
+	for k, v := range s.virtualCapabilities {
+		n += int64(32 + len(k) + len(v))
+	}
+
+	return n
+}
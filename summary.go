@@ -0,0 +1,124 @@
+package gowurfl
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// FormFactor is the typed value of the form_factor virtual capability.
+type FormFactor int
+
+const (
+	FormFactorUnknown FormFactor = iota
+	FormFactorDesktop
+	FormFactorSmartphone
+	FormFactorTablet
+	FormFactorFeaturePhone
+	FormFactorSmartTV
+	FormFactorRobot
+	FormFactorOtherNonMobile
+	FormFactorOtherMobile
+	FormFactorApp
+)
+
+var formFactorNames = []string{
+	FormFactorUnknown:        "",
+	FormFactorDesktop:        "Desktop",
+	FormFactorSmartphone:     "Smartphone",
+	FormFactorTablet:         "Tablet",
+	FormFactorFeaturePhone:   "Feature Phone",
+	FormFactorSmartTV:        "Smart-TV",
+	FormFactorRobot:          "Robot",
+	FormFactorOtherNonMobile: "Other non-Mobile",
+	FormFactorOtherMobile:    "Other Mobile",
+	FormFactorApp:            "App",
+}
+
+// String returns the value libwurfl uses for the form factor, e.g. "Feature
+// Phone", and "" for FormFactorUnknown.
+func (f FormFactor) String() string {
+	if f < 0 || int(f) >= len(formFactorNames) {
+		return ""
+	}
+
+	return formFactorNames[f]
+}
+
+// ParseFormFactor returns the form factor for a value of form_factor,
+// FormFactorUnknown if there is none.
+func ParseFormFactor(s string) FormFactor {
+	for f, name := range formFactorNames {
+		if name == s {
+			return FormFactor(f)
+		}
+	}
+
+	return FormFactorUnknown
+}
+
+// Summary holds the virtual capabilities most business logic needs, converted
+// to proper types.
+type Summary struct {
+	FormFactor         FormFactor
+	IsMobile           bool
+	IsSmartphone       bool
+	IsRobot            bool
+	CompleteDeviceName string
+	Browser            string
+	BrowserVersion     Version
+	OS                 string
+	OSVersion          Version
+}
+
+// summaryCapabilities are the virtual capabilities read by Summary.
+var summaryCapabilities = []string{
+	"form_factor",
+	"is_mobile",
+	"is_smartphone",
+	"is_robot",
+	"complete_device_name",
+	"advertised_browser",
+	"advertised_browser_version",
+	"advertised_device_os",
+	"advertised_device_os_version",
+}
+
+// Summary reads the virtual capabilities behind Summary from the device.
+func (d *Device) Summary() (Summary, error) {
+	return summarize(d.GetVirtualCapability)
+}
+
+// Summary reads the virtual capabilities behind Summary from the snapshot.
+func (s *Snapshot) Summary() (Summary, error) {
+	return summarize(s.GetVirtualCapability)
+}
+
+func summarize(get func(string) (string, error)) (Summary, error) {
+	vs := make(map[string]string, len(summaryCapabilities))
+	for _, name := range summaryCapabilities {
+		v, err := get(name)
+		if err != nil {
+			return Summary{}, fmt.Errorf("%s: %w", name, err)
+		}
+		vs[name] = v
+	}
+
+	s := Summary{
+		FormFactor:         ParseFormFactor(vs["form_factor"]),
+		CompleteDeviceName: vs["complete_device_name"],
+		Browser:            vs["advertised_browser"],
+		BrowserVersion:     ParseVersion(vs["advertised_browser_version"]),
+		OS:                 vs["advertised_device_os"],
+		OSVersion:          ParseVersion(vs["advertised_device_os_version"]),
+	}
+
+	for name, b := range map[string]*bool{"is_mobile": &s.IsMobile, "is_smartphone": &s.IsSmartphone, "is_robot": &s.IsRobot} {
+		v, err := strconv.ParseBool(vs[name])
+		if err != nil {
+			return Summary{}, fmt.Errorf("%s: %q: %w", name, vs[name], ErrorInvalidCapabilityValue)
+		}
+		*b = v
+	}
+
+	return s, nil
+}
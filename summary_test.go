@@ -0,0 +1,103 @@
+package gowurfl
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseFormFactor(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  FormFactor
+	}{
+		{"Desktop", FormFactorDesktop},
+		{"Smartphone", FormFactorSmartphone},
+		{"Tablet", FormFactorTablet},
+		{"Feature Phone", FormFactorFeaturePhone},
+		{"Smart-TV", FormFactorSmartTV},
+		{"Robot", FormFactorRobot},
+		{"Other non-Mobile", FormFactorOtherNonMobile},
+		{"Other Mobile", FormFactorOtherMobile},
+		{"App", FormFactorApp},
+		{"", FormFactorUnknown},
+		{"Other Non-Mobile", FormFactorUnknown},
+		{"Wristwatch", FormFactorUnknown},
+	} {
+		if f := ParseFormFactor(tc.value); f != tc.want {
+			t.Errorf("ParseFormFactor(%q) returned %d, want %d", tc.value, f, tc.want)
+		}
+
+		if tc.want != FormFactorUnknown && tc.want.String() != tc.value {
+			t.Errorf("%d.String() returned %q, want %q", tc.want, tc.want.String(), tc.value)
+		}
+	}
+}
+
+func testSummarySnapshot() *Snapshot {
+	return &Snapshot{
+		id: "apple_iphone_ver10_3",
+		virtualCapabilities: Capabilities{
+			"form_factor":                  "Smartphone",
+			"is_mobile":                    "true",
+			"is_smartphone":                "true",
+			"is_robot":                     "false",
+			"complete_device_name":         "Apple iPhone",
+			"advertised_browser":           "Mobile Safari",
+			"advertised_browser_version":   "10.0",
+			"advertised_device_os":         "iOS",
+			"advertised_device_os_version": "10.3.1",
+		},
+	}
+}
+
+func TestSnapshotSummary(t *testing.T) {
+	s, err := testSummarySnapshot().Summary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Summary{
+		FormFactor:         FormFactorSmartphone,
+		IsMobile:           true,
+		IsSmartphone:       true,
+		CompleteDeviceName: "Apple iPhone",
+		Browser:            "Mobile Safari",
+		BrowserVersion:     Version{"10.0", []int{10, 0}},
+		OS:                 "iOS",
+		OSVersion:          Version{"10.3.1", []int{10, 3, 1}},
+	}
+
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("Summary()\nwant: %+v\nhave: %+v", want, s)
+	}
+
+	snap := testSummarySnapshot()
+	snap.virtualCapabilities["is_robot"] = "maybe"
+	if _, err := snap.Summary(); !errors.Is(err, ErrorInvalidCapabilityValue) {
+		t.Errorf("Summary() with an invalid is_robot returned %v", err)
+	}
+
+	delete(snap.virtualCapabilities, "form_factor")
+	if _, err := snap.Summary(); !errors.Is(err, ErrorVirtualCapabilityNotFound) {
+		t.Errorf("Summary() without form_factor returned %v", err)
+	}
+}
+
+func TestDeviceSummary(t *testing.T) {
+	w := testNewEngine(t)
+	defer w.Close()
+	testLoadRepository(rootFile, w, t)
+
+	for _, ua := range uas {
+		d, err := w.LookupUserAgent(ua)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := d.Summary(); err != nil {
+			t.Errorf("Summary() for %q failed with: %s", ua, err)
+		}
+		d.Close()
+	}
+}
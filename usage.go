@@ -0,0 +1,144 @@
+package gowurfl
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CapabilityRecorder records the names of the capabilities and virtual
+// capabilities queried through the devices and snapshots of the engines it is
+// attached to, see SetCapabilityRecorder. Queries of capabilities that are not
+// loaded are recorded as well, so a profile recorded by an engine restricted
+// to an older profile still lists everything the application asks for.
+// A recorder may be shared by several engines, e.g. across reloads, and is
+// safe for concurrent use.
+type CapabilityRecorder struct {
+	stopped atomic.Bool
+	caps    sync.Map
+	vcaps   sync.Map
+}
+
+// NewCapabilityRecorder returns a recorder that records until Stop is called.
+func NewCapabilityRecorder() *CapabilityRecorder {
+	return &CapabilityRecorder{}
+}
+
+// Stop ends the recording. The names recorded so far are kept.
+func (r *CapabilityRecorder) Stop() {
+	r.stopped.Store(true)
+}
+
+// StopAfter ends the recording once the warm-up period d has passed and then
+// calls f, if not nil, with the profile recorded, e.g. to write it out.
+func (r *CapabilityRecorder) StopAfter(d time.Duration, f func(*CapabilityProfile)) {
+	time.AfterFunc(d, func() {
+		r.Stop()
+
+		if f != nil {
+			f(r.Profile())
+		}
+	})
+}
+
+// Profile returns the names recorded so far.
+func (r *CapabilityRecorder) Profile() *CapabilityProfile {
+	return &CapabilityProfile{
+		Capabilities:        recordedNames(&r.caps),
+		VirtualCapabilities: recordedNames(&r.vcaps),
+	}
+}
+
+func (r *CapabilityRecorder) capability(name string) {
+	if r != nil && !r.stopped.Load() {
+		r.caps.LoadOrStore(name, struct{}{})
+	}
+}
+
+func (r *CapabilityRecorder) virtualCapability(name string) {
+	if r != nil && !r.stopped.Load() {
+		r.vcaps.LoadOrStore(name, struct{}{})
+	}
+}
+
+func recordedNames(m *sync.Map) []string {
+	names := []string{}
+	m.Range(func(k, _ any) bool {
+		names = append(names, k.(string))
+		return true
+	})
+	sort.Strings(names)
+
+	return names
+}
+
+// SetCapabilityRecorder attaches a CapabilityRecorder to the engine. Devices
+// and snapshots record into the recorder attached when they were returned, so
+// it should be attached before Load. Passing nil detaches the recorder.
+func (w *WURFL) SetCapabilityRecorder(r *CapabilityRecorder) {
+	w.recorder = r
+}
+
+// CapabilityProfile lists the capabilities and virtual capabilities an
+// application uses. Passed to WithCapabilityProfile it restricts loading to
+// them.
+type CapabilityProfile struct {
+	Capabilities        []string `json:"capabilities"`
+	VirtualCapabilities []string `json:"virtual_capabilities"`
+}
+
+// Merge adds the names of o that are not in p yet, e.g. to combine the
+// profiles recorded by several instances of an application.
+func (p *CapabilityProfile) Merge(o *CapabilityProfile) {
+	p.Capabilities = mergeNames(p.Capabilities, o.Capabilities)
+	p.VirtualCapabilities = mergeNames(p.VirtualCapabilities, o.VirtualCapabilities)
+}
+
+func mergeNames(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	names := []string{}
+
+	for _, l := range [][]string{a, b} {
+		for _, n := range l {
+			if !seen[n] {
+				seen[n] = true
+				names = append(names, n)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ReadCapabilityProfile reads a profile written by WriteCapabilityProfile.
+func ReadCapabilityProfile(r io.Reader) (*CapabilityProfile, error) {
+	p := &CapabilityProfile{}
+	if err := json.NewDecoder(r).Decode(p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// WriteCapabilityProfile writes the profile as indented JSON.
+func WriteCapabilityProfile(w io.Writer, p *CapabilityProfile) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(p)
+}
+
+func readCapabilityProfileFile(p string) (*CapabilityProfile, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadCapabilityProfile(f)
+}
@@ -0,0 +1,150 @@
+package gowurfl
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCapabilityRecorder(t *testing.T) {
+	r := NewCapabilityRecorder()
+	s := &Snapshot{
+		capabilities:        Capabilities{"brand_name": "Apple"},
+		virtualCapabilities: Capabilities{"is_mobile": "true"},
+		recorder:            r,
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.GetCapability("brand_name")
+			s.GetCapability("model_name")
+			s.GetVirtualCapability("is_mobile")
+		}()
+	}
+	wg.Wait()
+
+	r.Stop()
+	s.GetCapability("resolution_width")
+
+	want := &CapabilityProfile{
+		Capabilities:        []string{"brand_name", "model_name"},
+		VirtualCapabilities: []string{"is_mobile"},
+	}
+	if p := r.Profile(); !reflect.DeepEqual(p, want) {
+		t.Errorf("Profile()\nwant: %+v\nhave: %+v", want, p)
+	}
+}
+
+func TestCapabilityRecorderBulk(t *testing.T) {
+	r := NewCapabilityRecorder()
+	s := &Snapshot{
+		capabilities:        Capabilities{"brand_name": "Apple", "model_name": "iPhone"},
+		virtualCapabilities: Capabilities{"is_mobile": "true"},
+		recorder:            r,
+	}
+
+	if _, err := s.GetCapabilities(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GetVirtualCapabilities(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &CapabilityProfile{
+		Capabilities:        []string{"brand_name", "model_name"},
+		VirtualCapabilities: []string{"is_mobile"},
+	}
+	if p := r.Profile(); !reflect.DeepEqual(p, want) {
+		t.Errorf("Profile()\nwant: %+v\nhave: %+v", want, p)
+	}
+}
+
+func TestCapabilityRecorderStopAfter(t *testing.T) {
+	r := NewCapabilityRecorder()
+	s := &Snapshot{recorder: r}
+	s.GetCapability("brand_name")
+
+	done := make(chan *CapabilityProfile)
+	r.StopAfter(time.Millisecond, func(p *CapabilityProfile) { done <- p })
+
+	select {
+	case p := <-done:
+		if !reflect.DeepEqual(p.Capabilities, []string{"brand_name"}) {
+			t.Errorf("unexpected capabilities %v", p.Capabilities)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("StopAfter did not call f")
+	}
+
+	s.GetCapability("model_name")
+	if p := r.Profile(); len(p.Capabilities) != 1 {
+		t.Errorf("recorded %v after the warm-up period", p.Capabilities)
+	}
+}
+
+func TestCapabilityProfileMerge(t *testing.T) {
+	p := &CapabilityProfile{Capabilities: []string{"model_name", "brand_name"}}
+	p.Merge(&CapabilityProfile{
+		Capabilities:        []string{"brand_name", "is_tablet"},
+		VirtualCapabilities: []string{"form_factor"},
+	})
+
+	want := &CapabilityProfile{
+		Capabilities:        []string{"brand_name", "is_tablet", "model_name"},
+		VirtualCapabilities: []string{"form_factor"},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Merge\nwant: %+v\nhave: %+v", want, p)
+	}
+}
+
+func TestCapabilityProfileRoundTrip(t *testing.T) {
+	p := &CapabilityProfile{
+		Capabilities:        []string{"brand_name"},
+		VirtualCapabilities: []string{"is_mobile"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCapabilityProfile(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+
+	have, err := ReadCapabilityProfile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(have, p) {
+		t.Errorf("read %+v, wrote %+v", have, p)
+	}
+}
+
+func TestCapabilityRecorderSharedCache(t *testing.T) {
+	c := NewResultCache(ResultCacheConfig{})
+	old, cur := NewCapabilityRecorder(), NewCapabilityRecorder()
+
+	s := testSnapshot("generic")
+	s.recorder = old
+	c.Add("ua", s)
+
+	w := &WURFL{cache: c, recorder: cur}
+	hit, err := w.LookupSnapshot("ua")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hit.GetCapability("brand_name")
+
+	if p := cur.Profile(); !reflect.DeepEqual(p.Capabilities, []string{"brand_name"}) {
+		t.Errorf("the engine's recorder has %v, want [brand_name]", p.Capabilities)
+	}
+
+	if p := old.Profile(); len(p.Capabilities) != 0 {
+		t.Errorf("the recorder of the engine that cached the snapshot has %v", p.Capabilities)
+	}
+}
@@ -0,0 +1,242 @@
+package gowurfl
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// CapabilityType is the type of the values of a capability. libwurfl hands
+// out all values as strings, the type says how they have to be interpreted.
+type CapabilityType int
+
+const (
+	CapabilityTypeString CapabilityType = iota
+	CapabilityTypeBool
+	CapabilityTypeInt
+	CapabilityTypeEnum
+)
+
+func (t CapabilityType) String() string {
+	switch t {
+	default:
+		return "string"
+	case CapabilityTypeBool:
+		return "bool"
+	case CapabilityTypeInt:
+		return "int"
+	case CapabilityTypeEnum:
+		return "enum"
+	}
+}
+
+// CapabilitySpec declares the type of a capability and, for enums, the
+// allowed values.
+type CapabilitySpec struct {
+	Type   CapabilityType
+	Values []string
+}
+
+// CapabilitySpecs holds the types of well known capabilities, including all
+// enums. It is kept by hand: neither libwurfl nor the repository declare the
+// types, see CapabilitySpecs of WURFL for the types of the other capabilities.
+// Add to it before validating to cover further enums.
+var CapabilitySpecs = map[string]CapabilitySpec{
+	"ajax_support_javascript": {Type: CapabilityTypeBool},
+	"brand_name":              {Type: CapabilityTypeString},
+	"can_assign_phone_number": {Type: CapabilityTypeBool},
+	"colors":                  {Type: CapabilityTypeInt},
+	"columns":                 {Type: CapabilityTypeInt},
+	"device_os":               {Type: CapabilityTypeString},
+	"device_os_version":       {Type: CapabilityTypeString},
+	"dual_orientation":        {Type: CapabilityTypeBool},
+	"is_smarttv":              {Type: CapabilityTypeBool},
+	"is_tablet":               {Type: CapabilityTypeBool},
+	"is_wireless_device":      {Type: CapabilityTypeBool},
+	"marketing_name":          {Type: CapabilityTypeString},
+	"max_image_height":        {Type: CapabilityTypeInt},
+	"max_image_width":         {Type: CapabilityTypeInt},
+	"mobile_browser":          {Type: CapabilityTypeString},
+	"mobile_browser_version":  {Type: CapabilityTypeString},
+	"model_name":              {Type: CapabilityTypeString},
+	"physical_screen_height":  {Type: CapabilityTypeInt},
+	"physical_screen_width":   {Type: CapabilityTypeInt},
+	"pointing_method":         {Type: CapabilityTypeEnum, Values: []string{"", "joystick", "stylus", "touchscreen", "clickwheel"}},
+	"preferred_markup":        {Type: CapabilityTypeString},
+	"resolution_height":       {Type: CapabilityTypeInt},
+	"resolution_width":        {Type: CapabilityTypeInt},
+	"rows":                    {Type: CapabilityTypeInt},
+	"ux_full_desktop":         {Type: CapabilityTypeBool},
+	"xhtml_support_level":     {Type: CapabilityTypeInt},
+}
+
+// ValidationError reports a capability value that does not match the declared
+// type. It matches ErrorInvalidCapabilityValue with errors.Is.
+type ValidationError struct {
+	DeviceID   string
+	Capability string
+	Value      string
+	Type       CapabilityType
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s: %q is not a valid %s", e.DeviceID, e.Capability, e.Value, e.Type)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrorInvalidCapabilityValue
+}
+
+// ValidateCapabilityValue checks value against the type of the capability in
+// CapabilitySpecs. Capabilities without a CapabilitySpec are always valid.
+func ValidateCapabilityValue(name, value string) bool {
+	spec, ok := CapabilitySpecs[name]
+	if !ok {
+		return true
+	}
+
+	return spec.valid(value)
+}
+
+func (spec CapabilitySpec) valid(value string) bool {
+	switch spec.Type {
+	case CapabilityTypeBool:
+		return value == "true" || value == "false"
+	case CapabilityTypeInt:
+		_, err := strconv.Atoi(value)
+		return err == nil
+	case CapabilityTypeEnum:
+		for _, v := range spec.Values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// CapabilitySpecs returns the types of all loaded capabilities. The repository
+// does not declare them, so unless listed in the package level CapabilitySpecs
+// they are derived from the generic device, which defines every capability:
+// a capability defaulting to "true" or "false" is a bool, one defaulting to an
+// integer an int and any other a string. Enums cannot be told from strings by
+// their default and are only known from the package level CapabilitySpecs.
+func (w *WURFL) CapabilitySpecs() (map[string]CapabilitySpec, error) {
+	d, err := w.GetDevice("generic")
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	defaults, err := d.capabilities()
+	if err != nil {
+		return nil, err
+	}
+
+	specs := make(map[string]CapabilitySpec, len(defaults))
+	for name, v := range defaults {
+		if spec, ok := CapabilitySpecs[name]; ok {
+			specs[name] = spec
+			continue
+		}
+
+		specs[name] = CapabilitySpec{Type: capabilityType(v)}
+	}
+
+	return specs, nil
+}
+
+// capabilityType returns the type of a capability with the default value v.
+func capabilityType(v string) CapabilityType {
+	if v == "true" || v == "false" {
+		return CapabilityTypeBool
+	}
+
+	if _, err := strconv.Atoi(v); err == nil {
+		return CapabilityTypeInt
+	}
+
+	return CapabilityTypeString
+}
+
+// ValidateCapabilities checks the values of the loaded capabilities of every
+// device against the types returned by CapabilitySpecs. An invalid value is
+// only reported for the device defining it, not for all devices inheriting
+// it: it is skipped if the parent has the same value.
+func (w *WURFL) ValidateCapabilities() ([]*ValidationError, error) {
+	specs, err := w.CapabilitySpecs()
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := w.GetDeviceIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	var found []*ValidationError
+
+	for _, id := range ids {
+		invalid, parent, err := w.invalidCapabilities(id, specs)
+		if err != nil {
+			return found, err
+		}
+
+		if len(invalid) == 0 {
+			continue
+		}
+
+		var inherited Capabilities
+		if parent != "" && parent != "root" {
+			p, err := w.GetDevice(parent)
+			if err != nil {
+				return found, err
+			}
+
+			inherited, err = p.capabilities()
+			p.Close()
+
+			if err != nil {
+				return found, err
+			}
+		}
+
+		for _, name := range sortedKeys(invalid) {
+			if v, ok := inherited[name]; ok && v == invalid[name] {
+				continue
+			}
+
+			found = append(found, &ValidationError{id, name, invalid[name], specs[name].Type})
+		}
+	}
+
+	return found, nil
+}
+
+// invalidCapabilities returns the values of the device not matching specs and
+// its parent's id.
+func (w *WURFL) invalidCapabilities(id string, specs map[string]CapabilitySpec) (map[string]string, string, error) {
+	d, err := w.GetDevice(id)
+	if err != nil {
+		return nil, "", err
+	}
+	defer d.Close()
+
+	caps, err := d.capabilities()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var invalid map[string]string
+	for name, v := range caps {
+		if spec, ok := specs[name]; ok && !spec.valid(v) {
+			if invalid == nil {
+				invalid = make(map[string]string)
+			}
+			invalid[name] = v
+		}
+	}
+
+	return invalid, d.GetParentID(), nil
+}
@@ -0,0 +1,101 @@
+package gowurfl
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateCapabilityValue(t *testing.T) {
+	tcs := []struct {
+		name  string
+		value string
+		valid bool
+	}{
+		{"is_tablet", "true", true},
+		{"is_tablet", "false", true},
+		{"is_tablet", "yes", false},
+		{"is_tablet", "", false},
+		{"resolution_width", "1080", true},
+		{"resolution_width", "1080px", false},
+		{"pointing_method", "touchscreen", true},
+		{"pointing_method", "", true},
+		{"pointing_method", "finger", false},
+		{"brand_name", "anything at all", true},
+		{"some_unknown_capability", "yes", true},
+	}
+
+	for _, tc := range tcs {
+		if v := ValidateCapabilityValue(tc.name, tc.value); v != tc.valid {
+			t.Errorf("ValidateCapabilityValue(%q, %q) returned %v, want %v", tc.name, tc.value, v, tc.valid)
+		}
+	}
+}
+
+func TestCapabilityType(t *testing.T) {
+	tcs := []struct {
+		value string
+		typ   CapabilityType
+	}{
+		{"false", CapabilityTypeBool},
+		{"true", CapabilityTypeBool},
+		{"0", CapabilityTypeInt},
+		{"-1", CapabilityTypeInt},
+		{"", CapabilityTypeString},
+		{"1.0", CapabilityTypeString},
+		{"html_web_4_0", CapabilityTypeString},
+	}
+
+	for _, tc := range tcs {
+		if typ := capabilityType(tc.value); typ != tc.typ {
+			t.Errorf("capabilityType(%q) returned %s, want %s", tc.value, typ, tc.typ)
+		}
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	var err error = &ValidationError{"generic_android", "is_tablet", "yes", CapabilityTypeBool}
+
+	if !errors.Is(err, ErrorInvalidCapabilityValue) {
+		t.Errorf("%v does not match ErrorInvalidCapabilityValue", err)
+	}
+
+	if want := `generic_android: is_tablet: "yes" is not a valid bool`; err.Error() != want {
+		t.Errorf("Error() returned %q, want %q", err.Error(), want)
+	}
+}
+
+func testValidateCapabilities(t *testing.T, patches ...string) []*ValidationError {
+	w := testNewEngine(t)
+	defer w.Close()
+
+	for _, p := range patches {
+		if err := w.AddPatch(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testLoadRepository(rootFile, w, t)
+
+	found, err := w.ValidateCapabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return found
+}
+
+func TestValidateCapabilities(t *testing.T) {
+	if found := testValidateCapabilities(t); len(found) != 0 {
+		t.Errorf("ValidateCapabilities() on the unpatched root returned %v", found)
+	}
+
+	want := []*ValidationError{
+		{"gowurfl_invalid_values", "is_tablet", "maybe", CapabilityTypeBool},
+		{"gowurfl_invalid_values", "resolution_width", "abc", CapabilityTypeInt},
+	}
+
+	found := testValidateCapabilities(t, "testdata/invalid_patch.xml")
+	if !reflect.DeepEqual(found, want) {
+		t.Errorf("ValidateCapabilities() with invalid values\nwant: %v\nhave: %v", want, found)
+	}
+}
@@ -0,0 +1,110 @@
+package gowurfl
+
+// #include <wurfl/wurfl.h>
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TestedVersions lists the libwurfl versions this package is tested against.
+var TestedVersions = []string{"1.7.1.0"}
+
+// ErrorUntestedVersion is returned by New if CompatibilityCheck is
+// VersionCheckError and the linked libwurfl is not in TestedVersions.
+var ErrorUntestedVersion = errors.New("untested libwurfl version")
+
+type VersionCheck int
+
+const (
+	// VersionCheckWarn logs a warning through slog.Default, once per process.
+	VersionCheckWarn VersionCheck = iota
+	// VersionCheckError makes New fail.
+	VersionCheckError
+	// VersionCheckOff disables the check.
+	VersionCheckOff
+)
+
+// CompatibilityCheck decides what New does when the linked libwurfl is not in
+// TestedVersions.
+var CompatibilityCheck = VersionCheckWarn
+
+var warnUntested sync.Once
+
+// APIVersion returns the version of the linked libwurfl as reported by the
+// library, e.g. "1.7.1.0".
+func APIVersion() string {
+	return C.GoString(C.wurfl_get_api_version())
+}
+
+// LibVersion is the numeric form of a libwurfl version.
+type LibVersion struct {
+	Major, Minor, Patch, Build int
+}
+
+func (v LibVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d.%d", v.Major, v.Minor, v.Patch, v.Build)
+}
+
+// AtLeast reports whether v is o or newer.
+func (v LibVersion) AtLeast(o LibVersion) bool {
+	a := [4]int{v.Major, v.Minor, v.Patch, v.Build}
+	b := [4]int{o.Major, o.Minor, o.Patch, o.Build}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] > b[i]
+		}
+	}
+
+	return true
+}
+
+// ParseLibVersion parses a dotted version string with up to four components.
+func ParseLibVersion(s string) (LibVersion, error) {
+	var c [4]int
+
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	if len(parts) > len(c) {
+		return LibVersion{}, fmt.Errorf("invalid libwurfl version %q", s)
+	}
+
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return LibVersion{}, fmt.Errorf("invalid libwurfl version %q", s)
+		}
+		c[i] = n
+	}
+
+	return LibVersion{c[0], c[1], c[2], c[3]}, nil
+}
+
+// checkCompatibility applies CompatibilityCheck to the linked libwurfl.
+func checkCompatibility() error {
+	if CompatibilityCheck == VersionCheckOff {
+		return nil
+	}
+
+	v := APIVersion()
+	for _, t := range TestedVersions {
+		if v == t {
+			return nil
+		}
+	}
+
+	if CompatibilityCheck == VersionCheckError {
+		return fmt.Errorf("%w %s, tested are %v", ErrorUntestedVersion, v, TestedVersions)
+	}
+
+	warnUntested.Do(func() {
+		slog.Warn("untested libwurfl version", "version", v, "tested", TestedVersions)
+	})
+
+	return nil
+}
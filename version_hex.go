@@ -0,0 +1,20 @@
+//go:build wurfl18
+
+package gowurfl
+
+// #include <wurfl/wurfl.h>
+import "C"
+
+// LibraryVersion returns the version of the linked libwurfl. Built with the
+// wurfl18 tag it is read from wurfl_get_api_version_hex, which requires the
+// headers of libwurfl 1.8 or newer.
+func LibraryVersion() (LibVersion, error) {
+	h := int64(C.wurfl_get_api_version_hex())
+
+	return LibVersion{
+		Major: int(h >> 24 & 0xff),
+		Minor: int(h >> 16 & 0xff),
+		Patch: int(h >> 8 & 0xff),
+		Build: int(h & 0xff),
+	}, nil
+}
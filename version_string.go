@@ -0,0 +1,10 @@
+//go:build !wurfl18
+
+package gowurfl
+
+// LibraryVersion returns the version of the linked libwurfl, parsed from
+// APIVersion. Build with the wurfl18 tag to read it from the numeric version
+// offered by newer headers instead.
+func LibraryVersion() (LibVersion, error) {
+	return ParseLibVersion(APIVersion())
+}
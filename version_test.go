@@ -0,0 +1,85 @@
+package gowurfl
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseLibVersion(t *testing.T) {
+	tcs := []struct {
+		in   string
+		out  LibVersion
+		fail bool
+	}{
+		{"1.7.1.0", LibVersion{1, 7, 1, 0}, false},
+		{"1.8", LibVersion{1, 8, 0, 0}, false},
+		{" 1.9.2.1\n", LibVersion{1, 9, 2, 1}, false},
+		{"1.7.1.0.3", LibVersion{}, true},
+		{"1.x", LibVersion{}, true},
+		{"", LibVersion{}, true},
+	}
+
+	for _, tc := range tcs {
+		v, err := ParseLibVersion(tc.in)
+		if (err != nil) != tc.fail {
+			t.Errorf("ParseLibVersion(%q) returned error %v", tc.in, err)
+		}
+
+		if v != tc.out {
+			t.Errorf("ParseLibVersion(%q) expected %v but got %v", tc.in, tc.out, v)
+		}
+	}
+}
+
+func TestLibVersionAtLeast(t *testing.T) {
+	tcs := []struct {
+		a, b LibVersion
+		out  bool
+	}{
+		{LibVersion{1, 7, 1, 0}, LibVersion{1, 7, 1, 0}, true},
+		{LibVersion{1, 8, 0, 0}, LibVersion{1, 7, 1, 0}, true},
+		{LibVersion{1, 7, 0, 9}, LibVersion{1, 7, 1, 0}, false},
+		{LibVersion{2, 0, 0, 0}, LibVersion{1, 9, 9, 9}, true},
+	}
+
+	for _, tc := range tcs {
+		if r := tc.a.AtLeast(tc.b); r != tc.out {
+			t.Errorf("%v.AtLeast(%v) expected %v but got %v", tc.a, tc.b, tc.out, r)
+		}
+	}
+}
+
+func TestLibraryVersion(t *testing.T) {
+	v, err := LibraryVersion()
+	if err != nil {
+		t.Fatalf("LibraryVersion() failed with: %s", err)
+	}
+
+	if v.Major == 0 {
+		t.Errorf("LibraryVersion() returned %v for %q", v, APIVersion())
+	}
+}
+
+func TestCompatibilityCheck(t *testing.T) {
+	defer func(tv []string, c VersionCheck) {
+		TestedVersions, CompatibilityCheck = tv, c
+	}(TestedVersions, CompatibilityCheck)
+
+	TestedVersions = []string{"0.0.0.0"}
+	CompatibilityCheck = VersionCheckError
+
+	if w, err := New(); !errors.Is(err, ErrorUntestedVersion) {
+		if err == nil {
+			w.Close()
+		}
+		t.Errorf("New() expected %v but got %v", ErrorUntestedVersion, err)
+	}
+
+	CompatibilityCheck = VersionCheckOff
+
+	w, err := New()
+	if err != nil {
+		t.Fatalf("New() failed with: %s", err)
+	}
+	w.Close()
+}